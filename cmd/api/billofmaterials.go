@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func (app *application) showBillOfMaterialsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	format := app.readFormat(r, v, "json", "csv")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	ids := make([]int64, 0, len(room.FurnitureList))
+	for _, val := range room.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	furniture, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	bom, err := data.NewBillOfMaterials(room, furniture)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if format == "json" {
+		err = app.writeJSON(w, http.StatusOK, envelope{"bill_of_materials": bom}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	records := [][]string{{"furniture_id", "name", "quantity", "unit_price", "total"}}
+	for _, line := range bom.Lines {
+		records = append(records, []string{
+			strconv.FormatInt(line.FurnitureID, 10),
+			csvText(line.Name),
+			strconv.FormatInt(line.Quantity, 10),
+			line.UnitPrice.String(),
+			line.Total.String(),
+		})
+	}
+	records = append(records, []string{"", "Total", strconv.FormatInt(bom.Quantity, 10), "", bom.Total.String()})
+
+	headers := make(http.Header)
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d-bill-of-materials.csv"`, room.ID))
+
+	err = app.writeCSV(w, http.StatusOK, records, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
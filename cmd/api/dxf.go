@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/dxf"
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+func (app *application) showRoomPlanDXFHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	furniture, err := app.roomFurniture(room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	err = dxf.Write(&buf, roomDXF(room, furniture))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/vnd.dxf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d.dxf"`, room.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// roomDXF converts the room into a drawing with one block per catalog item,
+// named after it, and an insert of that block for every placement. DXF has
+// the y axis pointing up, so the room is flipped vertically and clockwise
+// rotations become counter-clockwise ones.
+func roomDXF(room *data.Room, furniture map[int64]*data.Furniture) *dxf.Drawing {
+	height := float64(room.Height)
+
+	d := &dxf.Drawing{
+		Outline: geometry.Rect{Width: float64(room.Width), Height: height}.Polygon(),
+	}
+
+	blocks := map[int64]string{}
+
+	for _, val := range room.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		name, ok := blocks[f.ID]
+		if !ok {
+			name = dxfBlockName(f)
+			blocks[f.ID] = name
+
+			width, depth := float64(f.Width), float64(f.Height)
+
+			var shape geometry.Shape = geometry.Rect{X: -width / 2, Y: -depth / 2, Width: width, Height: depth}.Polygon()
+			if f.Shape == data.Circle {
+				shape = geometry.Circle{Radius: math.Min(width, depth) / 2}
+			}
+
+			d.Blocks = append(d.Blocks, dxf.Block{Name: name, Shape: shape})
+		}
+
+		bounds := val.Footprint(f).Bounds()
+
+		d.Inserts = append(d.Inserts, dxf.Insert{
+			Block:    name,
+			Layer:    dxf.LayerFurniture,
+			X:        bounds.X + bounds.Width/2,
+			Y:        height - (bounds.Y + bounds.Height/2),
+			Rotation: normalizeRotation(-val.Rotation),
+		})
+	}
+
+	return d
+}
+
+// dxfBlockName names the block of a catalog item. The ID prefix lets an
+// import find the item again even if the name was changed meanwhile.
+func dxfBlockName(f *data.Furniture) string {
+	return fmt.Sprintf("F%d_%s", f.ID, dxf.BlockName(f.Name))
+}
+
+var dxfBlockNameRX = regexp.MustCompile(`^F(\d+)_(.*)$`)
+
+// roomFromDXF builds a room from the outline and the inserts of a drawing,
+// which must have an outline. Inserts of blocks which can't be matched to the
+// catalog, and closed shapes outside blocks, are reported as skipped.
+func (app *application) roomFromDXF(d *dxf.Drawing, userID int64) (*roomImport, error) {
+	bounds := d.Outline.Bounds()
+
+	room := &data.Room{
+		Width:  int64(math.Round(bounds.Width)),
+		Height: int64(math.Round(bounds.Height)),
+	}
+
+	names := make([]string, 0, len(d.Inserts))
+	for _, insert := range d.Inserts {
+		names = append(names, insert.Block)
+	}
+
+	matches, err := app.matchDXFBlocks(names, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &roomImport{
+		rooms:     []*data.Room{room},
+		furniture: map[int64]*data.Furniture{},
+	}
+
+	for _, insert := range d.Inserts {
+		block := d.Block(insert.Block)
+		if block == nil {
+			imp.issues = append(imp.issues, importIssue{Name: insert.Block, Reason: "block is not defined in the file"})
+			continue
+		}
+
+		f, ok := matches[strings.ToLower(insert.Block)]
+		if !ok {
+			imp.issues = append(imp.issues, importIssue{Name: insert.Block, Reason: "no catalog item matches the block name"})
+			continue
+		}
+
+		imp.furniture[f.ID] = f
+
+		// The base point of the block isn't necessarily the centre of its
+		// shape, so find where the centre ends up after scaling and turning.
+		b := block.Shape.Bounds()
+		bx, by := (b.X+b.Width/2)*insert.ScaleX, (b.Y+b.Height/2)*insert.ScaleY
+		sin, cos := math.Sincos(insert.Rotation * math.Pi / 180)
+
+		cx := insert.X + bx*cos - by*sin - bounds.X
+		cy := bounds.Y + bounds.Height - (insert.Y + bx*sin + by*cos)
+
+		placement := data.FurnitureList{
+			FurnitureID: f.ID,
+			Rotation:    normalizeRotation(-insert.Rotation),
+		}
+
+		width, height := geometry.RotatedSize(float64(f.Width), float64(f.Height), placement.Rotation)
+		placement.X = roundCoordinate(cx - width/2)
+		placement.Y = roundCoordinate(cy - height/2)
+
+		room.FurnitureList = append(room.FurnitureList, placement)
+	}
+
+	for _, shape := range d.Shapes {
+		imp.issues = append(imp.issues, importIssue{Name: shape.Layer, Reason: "shape is not part of a block, so it can't be matched to the catalog"})
+	}
+
+	return imp, nil
+}
+
+// matchDXFBlocks finds the catalog items for the given block names, keyed by
+// lower-cased block name. Blocks written by roomDXF are matched by ID as long
+// as the name still fits, all others by name with underscores read as spaces.
+// Only items visible to the user are considered.
+func (app *application) matchDXFBlocks(names []string, userID int64) (map[string]*data.Furniture, error) {
+	matches := map[string]*data.Furniture{}
+
+	var ids []int64
+	for _, name := range names {
+		if m := dxfBlockNameRX.FindStringSubmatch(name); m != nil {
+			id, _ := strconv.ParseInt(m[1], 10, 64)
+			ids = append(ids, id)
+		}
+	}
+
+	byID, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	wanted := map[string][]string{}
+
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if _, ok := matches[key]; ok {
+			continue
+		}
+
+		candidate := name
+		if m := dxfBlockNameRX.FindStringSubmatch(name); m != nil {
+			id, _ := strconv.ParseInt(m[1], 10, 64)
+			if f, ok := byID[id]; ok && f.ArchivedAt == nil && f.VisibleTo(userID) && strings.EqualFold(dxf.BlockName(f.Name), m[2]) {
+				matches[key] = f
+				continue
+			}
+			candidate = m[2]
+		}
+
+		candidate = strings.ToLower(strings.ReplaceAll(candidate, "_", " "))
+		candidates = append(candidates, candidate)
+		wanted[candidate] = append(wanted[candidate], key)
+	}
+
+	if len(candidates) == 0 {
+		return matches, nil
+	}
+
+	byName, err := app.models.Furniture.GetByNames(candidates, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for candidate, keys := range wanted {
+		if items, ok := byName[candidate]; ok {
+			for _, key := range keys {
+				matches[key] = items[0]
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// normalizeRotation brings an angle into [0, 360), dropping floating point
+// noise.
+func normalizeRotation(deg float64) float64 {
+	deg = math.Round(deg*1e6) / 1e6
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	if deg >= 360 || deg == 0 {
+		return 0
+	}
+	return deg
+}
+
+// roundCoordinate rounds an imported coordinate to whole units. Items drawn
+// flush against the outline may come out a fraction below zero, which is
+// still treated as zero.
+func roundCoordinate(f float64) int64 {
+	c := int64(math.Round(f))
+	if c < 0 && c >= -1 {
+		return 0
+	}
+	return c
+}
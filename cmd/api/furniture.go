@@ -12,7 +12,7 @@ import (
 func (app *application) createFurnitureHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Name        string     `json:"name"`
-		Price       float64    `json:"price"`
+		Price       data.Money `json:"price"`
 		Description string     `json:"description"`
 		Width       int64      `json:"width"`
 		Height      int64      `json:"height"`
@@ -43,7 +43,16 @@ func (app *application) createFurnitureHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.models.Furniture.Insert(furniture)
+	user := app.contextGetUser(r)
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.Furniture.Insert(furniture)
+		if err != nil {
+			return err
+		}
+
+		return tx.FurnitureChanges.Insert(user.ID, data.FurnitureCreated, furniture)
+	})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -77,6 +86,11 @@ func (app *application) showFurnitureHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !furniture.VisibleTo(app.contextGetUser(r).ID) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -103,7 +117,7 @@ func (app *application) updateFurnitureHandler(w http.ResponseWriter, r *http.Re
 
 	var input struct {
 		Name        *string     `json:"name"`
-		Price       *float64    `json:"price"`
+		Price       *data.Money `json:"price"`
 		Description *string     `json:"description"`
 		Width       *int64      `json:"width"`
 		Height      *int64      `json:"height"`
@@ -152,7 +166,16 @@ func (app *application) updateFurnitureHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.models.Furniture.Update(furniture)
+	user := app.contextGetUser(r)
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.Furniture.Update(furniture)
+		if err != nil {
+			return err
+		}
+
+		return tx.FurnitureChanges.Insert(user.ID, data.FurnitureUpdated, furniture)
+	})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -171,7 +194,27 @@ func (app *application) deleteFurnitureHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.models.Furniture.Delete(id)
+	furniture, err := app.models.Furniture.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.Furniture.Archive(furniture)
+		if err != nil {
+			return err
+		}
+
+		return tx.FurnitureChanges.Insert(user.ID, data.FurnitureArchived, furniture)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -182,20 +225,127 @@ func (app *application) deleteFurnitureHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "furniture successfully deleted"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "furniture successfully archived"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreFurnitureHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	furniture, err := app.models.Furniture.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.Furniture.Restore(furniture)
+		if err != nil {
+			return err
+		}
+
+		return tx.FurnitureChanges.Insert(user.ID, data.FurnitureRestored, furniture)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
 func (app *application) listFurnitureHandler(w http.ResponseWriter, r *http.Request) {
-	furniture, err := app.models.Furniture.GetAll()
+	var input struct {
+		Name      string     `json:"name"`
+		MinPrice  data.Money `json:"min_price"`
+		MaxPrice  data.Money `json:"max_price"`
+		MaxWidth  int        `json:"max_width"`
+		MaxHeight int        `json:"max_height"`
+		Shape     int        `json:"shape"`
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Name = app.readString(qs, "name", "")
+	input.MinPrice = app.readMoney(qs, "min_price", 0, v)
+	input.MaxPrice = app.readMoney(qs, "max_price", 0, v)
+	input.MaxWidth = app.readInt(qs, "max_width", 0, v)
+	input.MaxHeight = app.readInt(qs, "max_height", 0, v)
+	input.Shape = app.readInt(qs, "shape", -1, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+
+	input.Filters.SortSafelist = []string{"id", "name", "price", "size", "-id", "-name", "-price", "-size"}
+	input.Filters.SortColumns = map[string]string{
+		"id":   "furniture_id",
+		"size": "furniture_width * furniture_height",
+	}
+
+	v.Check(input.MinPrice >= 0, "min_price", "must not be negative")
+	v.Check(input.MaxPrice >= 0, "max_price", "must not be negative")
+	v.Check(input.MaxPrice == 0 || input.MaxPrice >= input.MinPrice, "max_price", "must not be less than min_price")
+	v.Check(input.MaxWidth >= 0, "max_width", "must not be negative")
+	v.Check(input.MaxHeight >= 0, "max_height", "must not be negative")
+	v.Check(input.Shape == -1 || input.Shape == int(data.Rectangle) ||
+		input.Shape == int(data.Circle), "shape", "must be a correct value")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	furniture, metadata, err := app.models.Furniture.GetAll(app.contextGetUser(r).ID, input.Name, input.MinPrice, input.MaxPrice,
+		input.MaxWidth, input.MaxHeight, data.Shape(input.Shape), input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFurnitureChangesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	changes, err := app.models.FurnitureChanges.GetAllForFurniture(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"changes": changes}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,22 +12,52 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/WrastAct/EHome/internal/data"
 	"github.com/WrastAct/EHome/internal/validator"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
 	return id, nil
 }
 
 type envelope map[string]interface{}
 
+// versionETag formats a record version as a strong entity tag.
+func versionETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ifMatch reports whether the If-Match header of the request allows a write to
+// a record at the given version. A request without the header always matches.
+func (app *application) ifMatch(r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+
+	etag := versionETag(version)
+
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 
 	js, err := json.MarshalIndent(data, "", "\t")
@@ -45,6 +77,65 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+func (app *application) writeCSV(w http.ResponseWriter, status int, records [][]string, headers http.Header) error {
+	var buf bytes.Buffer
+
+	err := csv.NewWriter(&buf).WriteAll(records)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+	return nil
+}
+
+// csvText prefixes user supplied text which spreadsheets would take for a
+// formula with a quote, so that it is shown as it is.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// formatMediaTypes maps the values of the format query string parameter to the
+// media types that select them in an Accept header.
+var formatMediaTypes = map[string]string{
+	"csv":  "text/csv",
+	"dxf":  "image/vnd.dxf",
+	"json": "application/json",
+	"sh3d": "application/x-sweethome3d",
+}
+
+func formatList(formats []string) string {
+	return strings.Join(formats, ", ")
+}
+
+// readFormat picks one of the offered response formats. An explicit format
+// query string parameter wins over the Accept header, and the first offered
+// format is the default.
+func (app *application) readFormat(r *http.Request, v *validator.Validator, offered ...string) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		v.Check(validator.In(format, offered...), "format", fmt.Sprintf("must be one of %s", formatList(offered)))
+		return format
+	}
+
+	accept := r.Header.Get("Accept")
+	for _, format := range offered {
+		if strings.Contains(accept, formatMediaTypes[format]) {
+			return format
+		}
+	}
+
+	return offered[0]
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 
 	maxBytes := 1_048_576
@@ -123,6 +214,38 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+func (app *application) readMoney(qs url.Values, key string, defaultValue data.Money, v *validator.Validator) data.Money {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	m, err := data.ParseMoney(s)
+	if err != nil {
+		v.AddError(key, "must be a decimal number with at most two decimals")
+		return defaultValue
+	}
+
+	return m
+}
+
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be true or false")
+		return defaultValue
+	}
+
+	return b
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 
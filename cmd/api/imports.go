@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/dxf"
+	"github.com/WrastAct/EHome/internal/roomdoc"
+	"github.com/WrastAct/EHome/internal/sh3d"
+	"github.com/WrastAct/EHome/internal/validator"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxImportBytes limits the size of uploaded room files.
+const maxImportBytes = 10 << 20
+
+// importIssue describes a part of an imported file which was left out of the
+// room.
+type importIssue struct {
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// postRoomHandler serves POST /v1/rooms/import. httprouter can't register a
+// static segment next to the :id wildcard of the other room routes, so the
+// route is POST /v1/rooms/:id and any other ID keeps answering as before.
+func (app *application) postRoomHandler(w http.ResponseWriter, r *http.Request) {
+	if httprouter.ParamsFromContext(r.Context()).ByName("id") != "import" {
+		w.Header().Set("Allow", "GET, PATCH, DELETE, OPTIONS")
+		app.methodNotAllowedResponse(w, r)
+		return
+	}
+
+	app.importRoomHandler(w, r)
+}
+
+func (app *application) importRoomHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	format := app.readImportFormat(r, v, "dxf", "sh3d", "json")
+	title := app.readString(qs, "title", "Imported room")
+	allowOverlap := app.readBool(qs, "allow_overlap", false, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var imp *roomImport
+
+	switch format {
+	case "dxf":
+		drawing, err := dxf.Read(r.Body)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if drawing.Outline == nil {
+			v.AddError("file", "must contain a closed room outline")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		imp, err = app.roomFromDXF(drawing, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		imp.rooms[0].Title = title
+
+	case "sh3d":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		home, err := sh3d.Read(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		imp, err = app.roomsFromSH3D(home, title, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if len(imp.rooms) == 0 {
+			v.AddError("file", "must contain rooms, walls or furniture")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+	case "json":
+		doc, err := roomdoc.Parse(r.Body)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if roomdoc.Validate(v, doc); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		imp, err = app.roomFromDocument(doc, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// The document names the room itself.
+		if qs.Get("title") != "" {
+			imp.rooms[0].Title = title
+		}
+	}
+
+	app.createImportedRooms(w, r, imp, allowOverlap)
+}
+
+// readImportFormat picks the format of the request body from the format query
+// string parameter or, failing that, from the Content-Type header.
+func (app *application) readImportFormat(r *http.Request, v *validator.Validator, offered ...string) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		v.Check(validator.In(format, offered...), "format", fmt.Sprintf("must be one of %s", formatList(offered)))
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	for _, format := range offered {
+		if mediaType == formatMediaTypes[format] {
+			return format
+		}
+	}
+
+	v.AddError("format", fmt.Sprintf("must be one of %s, or given as the Content-Type", formatList(offered)))
+	return ""
+}
+
+// roomImport holds the rooms read from an uploaded file until they are saved.
+type roomImport struct {
+	rooms     []*data.Room
+	furniture map[int64]*data.Furniture // Every item placed in the rooms, by ID
+	custom    []*data.Furniture         // Custom items to create, with temporary negative IDs
+	issues    []importIssue
+}
+
+// createImportedRooms validates the imported rooms like newly created ones and
+// saves them, together with any custom items they need, as private rooms of
+// the current user. The response lists the issues found while reading the
+// file.
+func (app *application) createImportedRooms(w http.ResponseWriter, r *http.Request, imp *roomImport, allowOverlap bool) {
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+
+	for i, room := range imp.rooms {
+		room.OwnerID = user.ID
+		room.Visibility = data.VisibilityPrivate
+
+		prefix := ""
+		if len(imp.rooms) > 1 {
+			prefix = fmt.Sprintf("rooms[%d].", i)
+		}
+
+		rv := validator.New()
+		data.ValidateRoom(rv, room)
+
+		for j, val := range room.FurnitureList {
+			item := validator.New()
+			if data.ValidateFurnitureList(item, &val); !item.Valid() {
+				for key, message := range item.Errors {
+					rv.AddError(fmt.Sprintf("furniture_list[%d].%s", j, key), message)
+				}
+			}
+		}
+
+		if rv.Valid() {
+			checkFurnitureLayout(rv, room, nil, imp.furniture, allowOverlap)
+		}
+
+		for key, message := range rv.Errors {
+			v.AddError(prefix+key, message)
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Transaction(func(tx data.Models) error {
+		ids := make(map[int64]int64, len(imp.custom))
+
+		for _, f := range imp.custom {
+			tempID := f.ID
+
+			err := tx.Furniture.Insert(f)
+			if err != nil {
+				return err
+			}
+
+			ids[tempID] = f.ID
+		}
+
+		for _, room := range imp.rooms {
+			err := tx.Room.Insert(room)
+			if err != nil {
+				return err
+			}
+
+			for key, val := range room.FurnitureList {
+				room.FurnitureList[key].RoomID = room.ID
+				if id, ok := ids[val.FurnitureID]; ok {
+					room.FurnitureList[key].FurnitureID = id
+				}
+			}
+
+			err = tx.FurnitureList.InsertAll(room.FurnitureList)
+			if err != nil {
+				return err
+			}
+
+			err = app.recordRevision(tx, room, user)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	custom := imp.custom
+	if custom == nil {
+		custom = []*data.Furniture{}
+	}
+
+	issues := imp.issues
+	if issues == nil {
+		issues = []importIssue{}
+	}
+
+	headers := make(http.Header)
+	if len(imp.rooms) == 1 {
+		headers.Set("Location", fmt.Sprintf("/v1/rooms/%d", imp.rooms[0].ID))
+		headers.Set("ETag", versionETag(imp.rooms[0].Version))
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"rooms": imp.rooms, "custom_furniture": custom, "skipped": issues}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// truncateName shortens an imported name to at most n bytes without cutting
+// a character in half.
+func truncateName(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return strings.TrimSpace(s[:n])
+}
@@ -48,14 +48,22 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	history struct {
+		maxRevisions int
+		maxAge       time.Duration
+	}
+	thumbnails struct {
+		cacheSize int
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config     config
+	logger     *jsonlog.Logger
+	models     data.Models
+	mailer     mailer.Mailer
+	thumbnails *thumbnailCache
+	wg         sync.WaitGroup
 }
 
 func main() {
@@ -84,6 +92,11 @@ func main() {
 		return nil
 	})
 
+	flag.IntVar(&cfg.history.maxRevisions, "history-max-revisions", 100, "Revisions kept per room (0 for no limit)")
+	flag.DurationVar(&cfg.history.maxAge, "history-max-age", 0, "Maximum age of room revisions (0 for no limit)")
+
+	flag.IntVar(&cfg.thumbnails.cacheSize, "thumbnail-cache-size", 1000, "Maximum number of cached room thumbnails")
+
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -120,10 +133,11 @@ func main() {
 	}))
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:     cfg,
+		logger:     logger,
+		models:     data.NewModels(db),
+		mailer:     mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		thumbnails: newThumbnailCache(cfg.thumbnails.cacheSize),
 	}
 
 	err = app.serve()
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func (app *application) createPlacementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	room, err := app.models.Room.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !data.RoleCanEdit(role) {
+		app.foreignRoomResponse(w, r)
+		return
+	}
+
+	if !app.ifMatch(r, room.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FurnitureID  int64   `json:"furniture_id"`
+		X            int64   `json:"x"`
+		Y            int64   `json:"y"`
+		Rotation     float64 `json:"rotation"`
+		AllowOverlap bool    `json:"allow_overlap"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	placement := &data.FurnitureList{
+		FurnitureID: input.FurnitureID,
+		RoomID:      room.ID,
+		X:           input.X,
+		Y:           input.Y,
+		Rotation:    input.Rotation,
+	}
+
+	v := validator.New()
+
+	if data.ValidateFurnitureList(v, placement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.validatePlacement(v, room, placement, input.AllowOverlap)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.FurnitureList.Insert(placement)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Room.Update(room)
+		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/rooms/%d/furniture/%d", room.ID, placement.ID))
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"placement": placement}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updatePlacementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	placementID, err := app.readNamedIDParam(r, "placement_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	room, err := app.models.Room.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !data.RoleCanEdit(role) {
+		app.foreignRoomResponse(w, r)
+		return
+	}
+
+	if !app.ifMatch(r, room.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	placement, err := app.models.FurnitureList.Get(room.ID, placementID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		FurnitureID  *int64   `json:"furniture_id"`
+		X            *int64   `json:"x"`
+		Y            *int64   `json:"y"`
+		Rotation     *float64 `json:"rotation"`
+		AllowOverlap bool     `json:"allow_overlap"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.FurnitureID != nil {
+		placement.FurnitureID = *input.FurnitureID
+	}
+
+	if input.X != nil {
+		placement.X = *input.X
+	}
+
+	if input.Y != nil {
+		placement.Y = *input.Y
+	}
+
+	if input.Rotation != nil {
+		placement.Rotation = *input.Rotation
+	}
+
+	v := validator.New()
+
+	if data.ValidateFurnitureList(v, placement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.validatePlacement(v, room, placement, input.AllowOverlap)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.FurnitureList.Update(placement)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Room.Update(room)
+		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"placement": placement}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePlacementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	placementID, err := app.readNamedIDParam(r, "placement_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	room, err := app.models.Room.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !data.RoleCanEdit(role) {
+		app.foreignRoomResponse(w, r)
+		return
+	}
+
+	if !app.ifMatch(r, room.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.FurnitureList.DeletePlacement(room.ID, placementID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Room.Update(room)
+		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "placement successfully deleted"}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validatePlacement checks a single placement against the current layout of
+// the room, which is loaded from the database.
+func (app *application) validatePlacement(v *validator.Validator, room *data.Room, placement *data.FurnitureList, allowOverlap bool) error {
+	furnitureList, err := app.models.FurnitureList.GetAll(room.ID)
+	if err != nil {
+		return err
+	}
+
+	room.FurnitureList = furnitureList
+
+	ids := []int64{placement.FurnitureID}
+	for _, val := range furnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	furniture, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	// Custom items of other users are treated as if they didn't exist.
+	if f, ok := furniture[placement.FurnitureID]; ok && !f.VisibleTo(room.OwnerID) {
+		delete(furniture, placement.FurnitureID)
+	}
+
+	data.ValidatePlacement(v, room, placement, furniture, allowOverlap)
+
+	if f, ok := furniture[placement.FurnitureID]; ok {
+		placement.SetFootprint(f)
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/render"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func (app *application) showRoomPlanSVGHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	opts := app.readPlanOptions(qs, v)
+	width := app.readInt(qs, "width", 0, v)
+
+	v.Check(width >= 0, "width", "must not be negative")
+	v.Check(width <= 10_000, "width", "must be a maximum of 10000")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	furniture, err := app.roomFurniture(room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	plan := roomPlan(room, furniture)
+
+	var buf bytes.Buffer
+
+	err = render.Layout(plan, opts).WriteSVG(&buf, float64(width))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (app *application) showRoomPlanPNGHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	opts := app.readPlanOptions(qs, v)
+	width := app.readInt(qs, "width", 800, v)
+
+	v.Check(width >= 16, "width", "must be at least 16")
+	v.Check(width <= 4000, "width", "must be a maximum of 4000")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Dimension annotations and the legend are mostly text, which the PNG
+	// output leaves out.
+	opts.Dimensions = false
+	opts.Legend = false
+
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	changes, err := app.models.FurnitureChanges.GetLatestForRooms([]int64{room.ID})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := thumbnailKey{roomID: room.ID, furnitureChange: changes[room.ID], width: width, opts: opts}
+
+	img, ok := app.thumbnails.get(key, room.Version)
+	if !ok {
+		furniture, err := app.roomFurniture(room)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		plan := roomPlan(room, furniture)
+		plan.Title = ""
+
+		var buf bytes.Buffer
+
+		drawing := render.Layout(plan, opts)
+
+		err = drawing.WritePNG(&buf, width)
+		if err != nil {
+			switch {
+			case errors.Is(err, render.ErrImageTooLarge):
+				v.AddError("width", fmt.Sprintf("gives a %d × %d image for this room, which is more than %d pixels",
+					width, drawing.PixelHeight(width), render.MaxPixels))
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		img = buf.Bytes()
+
+		if width <= thumbnailMaxWidth {
+			app.thumbnails.put(key, room.Version, img)
+		}
+	}
+
+	// Thumbnail URLs carry the room version and the latest change to its
+	// furniture, so what they point to never changes.
+	if qs.Get("v") == thumbnailVersion(room.Version, changes[room.ID]) {
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	w.Write(img)
+}
+
+func (app *application) showRoomPlanPDFHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	opts := app.readPlanOptions(r.URL.Query(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	owner, err := app.models.Users.Get(room.OwnerID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	furniture, err := app.roomFurniture(room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	plan := roomPlan(room, furniture)
+
+	// The title goes into the title block instead.
+	plan.Title = ""
+
+	bom, err := data.NewBillOfMaterials(room, furniture)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sheet := &render.Sheet{
+		Title: room.Title,
+		Details: []render.Detail{
+			{Label: "Description", Value: room.Description},
+			{Label: "Dimensions", Value: fmt.Sprintf("%d × %d", room.Width, room.Height)},
+			{Label: "Owner", Value: owner.Name},
+			{Label: "Created", Value: room.Date.Format("2006-01-02")},
+			{Label: "Version", Value: strconv.Itoa(room.Version)},
+		},
+		Schedule: render.Table{
+			Columns: []string{"Item", "Qty", "Unit price", "Total"},
+			Widths:  []float64{3, 1, 1.5, 1.5},
+			Footer:  []string{"Total", strconv.FormatInt(bom.Quantity, 10), "", bom.Total.String()},
+		},
+	}
+
+	if room.Description == "" {
+		sheet.Details = sheet.Details[1:]
+	}
+
+	if room.Budget != nil {
+		sheet.Details = append(sheet.Details, render.Detail{Label: "Budget", Value: room.Budget.String()})
+	}
+
+	for _, line := range bom.Lines {
+		sheet.Schedule.Rows = append(sheet.Schedule.Rows, []string{
+			line.Name,
+			strconv.FormatInt(line.Quantity, 10),
+			line.UnitPrice.String(),
+			line.Total.String(),
+		})
+	}
+
+	var buf bytes.Buffer
+
+	err = render.WritePDF(&buf, render.Layout(plan, opts), sheet)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="room-%d-plan.pdf"`, room.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// readPlanOptions reads the grid, grid_spacing, dimensions and legend query
+// string parameters shared by the floor plan formats.
+func (app *application) readPlanOptions(qs url.Values, v *validator.Validator) render.Options {
+	opts := render.Options{
+		Grid:        app.readBool(qs, "grid", false, v),
+		GridSpacing: float64(app.readInt(qs, "grid_spacing", 100, v)),
+		Dimensions:  app.readBool(qs, "dimensions", false, v),
+		Legend:      app.readBool(qs, "legend", false, v),
+	}
+
+	v.Check(opts.GridSpacing > 0, "grid_spacing", "must be greater than zero")
+
+	return opts
+}
+
+// roomFurniture loads the catalog items placed in the room.
+func (app *application) roomFurniture(room *data.Room) (map[int64]*data.Furniture, error) {
+	ids := make([]int64, 0, len(room.FurnitureList))
+	for _, val := range room.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	return app.models.Furniture.GetByIDs(ids)
+}
+
+// roomPlan converts the room and its placements into a floor plan.
+func roomPlan(room *data.Room, furniture map[int64]*data.Furniture) *render.Plan {
+	plan := &render.Plan{
+		Title:  room.Title,
+		Width:  float64(room.Width),
+		Height: float64(room.Height),
+	}
+
+	for _, val := range room.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		plan.Items = append(plan.Items, render.Item{
+			Label:  f.Name,
+			Group:  f.ID,
+			Shape:  val.Footprint(f),
+			Width:  float64(f.Width),
+			Height: float64(f.Height),
+		})
+	}
+
+	return plan
+}
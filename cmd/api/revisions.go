@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func (app *application) listRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readMemberRoom(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetAllForRoom(room.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	revisionID, err := app.readNamedIDParam(r, "revision_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	room, ok := app.readMemberRoom(w, r)
+	if !ok {
+		return
+	}
+
+	revision, err := app.models.Revisions.Get(room.ID, revisionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revision": revision}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	revisionID, err := app.readNamedIDParam(r, "revision_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	room, ok := app.readMemberRoom(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !data.RoleCanEdit(role) {
+		app.foreignRoomResponse(w, r)
+		return
+	}
+
+	if !app.ifMatch(r, room.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	revision, err := app.models.Revisions.Get(room.ID, revisionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	previous, err := app.models.FurnitureList.GetAll(room.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Visibility is an access setting rather than part of the design, so it
+	// is left as it currently is.
+	room.Title = revision.Room.Title
+	room.Description = revision.Room.Description
+	room.Width = revision.Room.Width
+	room.Height = revision.Room.Height
+	room.Budget = revision.Room.Budget
+	room.FurnitureList = revision.Room.FurnitureList
+
+	placed := make(map[int64]bool, len(previous))
+	for _, val := range previous {
+		placed[val.ID] = true
+	}
+
+	// Placements which are still in the room keep their IDs, the ones deleted
+	// since the snapshot come back as new placements.
+	for i, val := range room.FurnitureList {
+		room.FurnitureList[i].RoomID = room.ID
+		if !placed[val.ID] {
+			room.FurnitureList[i].ID = 0
+		}
+	}
+
+	v := validator.New()
+
+	err = app.validateFurnitureIDs(v, room.OwnerID, room.FurnitureList)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The snapshot was a valid layout when it was taken, including any
+	// overlaps the client allowed back then. Discontinued items it contains
+	// may come back as well.
+	err = app.validateFurnitureLayout(v, room, append(previous, room.FurnitureList...), true)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.FurnitureList.ReplaceAll(room.ID, room.FurnitureList)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Room.Update(room)
+		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"room": room}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// recordRevision stores a snapshot of the room with its current placements and
+// prunes the history according to the retention settings. Call it with the
+// transaction which changed the room, after the change has been written.
+func (app *application) recordRevision(tx data.Models, room *data.Room, user *data.User) error {
+	furnitureList, err := tx.FurnitureList.GetAll(room.ID)
+	if err != nil {
+		return err
+	}
+
+	snapshot := *room
+	snapshot.FurnitureList = furnitureList
+
+	err = tx.Revisions.Insert(&snapshot, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Revisions.Prune(room.ID, app.config.history.maxRevisions, app.config.history.maxAge)
+}
+
+// readMemberRoom loads the room from the id URL parameter and checks that the
+// current user is its owner or one of its collaborators. If not, an error
+// response has already been sent and ok is false.
+func (app *application) readMemberRoom(w http.ResponseWriter, r *http.Request) (*data.Room, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	room, err := app.models.Room.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	role, err := app.roomRole(app.contextGetUser(r), room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if role == "" {
+		app.foreignRoomResponse(w, r)
+		return nil, false
+	}
+
+	return room, true
+}
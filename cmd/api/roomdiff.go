@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/WrastAct/EHome/internal/data"
+)
+
+func (app *application) compareRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	other, ok := app.readViewableRoom(w, r, "other_id")
+	if !ok {
+		return
+	}
+
+	ids := make([]int64, 0, len(room.FurnitureList)+len(other.FurnitureList))
+	for _, val := range room.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+	for _, val := range other.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	furniture, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	diff, err := data.DiffRooms(room, other, furniture)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"diff": diff}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readViewableRoom loads the room identified by the named URL parameter along
+// with its placements, and checks that the current user may see it. If not, an
+// error response has already been sent and ok is false.
+func (app *application) readViewableRoom(w http.ResponseWriter, r *http.Request, param string) (*data.Room, bool) {
+	id, err := app.readNamedIDParam(r, param)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	room, err := app.models.Room.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	canView, err := app.canViewRoom(app.contextGetUser(r), room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if !canView {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	furnitureList, err := app.models.FurnitureList.GetAll(room.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	room.FurnitureList = furnitureList
+
+	return room, true
+}
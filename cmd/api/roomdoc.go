@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/roomdoc"
+)
+
+func (app *application) exportRoomHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	furniture, err := app.roomFurniture(room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	js, err := json.MarshalIndent(roomdoc.New(room, furniture, time.Now()), "", "\t")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d.json"`, room.ID))
+	w.Header().Set("ETag", versionETag(room.Version))
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(js, '\n'))
+}
+
+// roomFromDocument builds a room from a document, mapping its furniture to
+// the items visible to the user. Items which can't be mapped are reported as
+// skipped together with their placements.
+func (app *application) roomFromDocument(doc *roomdoc.Document, userID int64) (*roomImport, error) {
+	room := &data.Room{
+		Title:       doc.Room.Title,
+		Description: doc.Room.Description,
+		Width:       doc.Room.Width,
+		Height:      doc.Room.Height,
+		Budget:      doc.Room.Budget,
+	}
+
+	imp := &roomImport{
+		rooms:     []*data.Room{room},
+		furniture: map[int64]*data.Furniture{},
+	}
+
+	var ids []int64
+	var names []string
+
+	for _, f := range doc.Furniture {
+		if f.CatalogID > 0 {
+			ids = append(ids, f.CatalogID)
+		}
+		if f.HasSpec() {
+			names = append(names, f.Name)
+		}
+	}
+
+	byID, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var byName map[string][]*data.Furniture
+	if len(names) > 0 {
+		byName, err = app.models.Furniture.GetByNames(names, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches := map[string]*data.Furniture{}
+
+	for _, f := range doc.Furniture {
+		if item := matchDocumentFurniture(f, byID, byName, userID); item != nil {
+			matches[f.Ref] = item
+			imp.furniture[item.ID] = item
+		}
+	}
+
+	skipped := map[string]int{}
+
+	for _, p := range doc.Placements {
+		item, ok := matches[p.Furniture]
+		if !ok {
+			skipped[p.Furniture]++
+			continue
+		}
+
+		room.FurnitureList = append(room.FurnitureList, data.FurnitureList{
+			FurnitureID: item.ID,
+			X:           p.X,
+			Y:           p.Y,
+			Rotation:    p.Rotation,
+		})
+	}
+
+	for _, f := range doc.Furniture {
+		if _, ok := matches[f.Ref]; ok {
+			continue
+		}
+
+		name := f.Name
+		if name == "" {
+			name = fmt.Sprintf("catalog item %d", f.CatalogID)
+		}
+
+		reason := "no catalog item matches it"
+		switch n := skipped[f.Ref]; n {
+		case 0:
+		case 1:
+			reason += ", so its placement was left out"
+		default:
+			reason += fmt.Sprintf(", so its %d placements were left out", n)
+		}
+
+		imp.issues = append(imp.issues, importIssue{Name: name, Reason: reason})
+	}
+
+	return imp, nil
+}
+
+// matchDocumentFurniture finds the item for a document entry. The catalog ID
+// is trusted as long as the item there still has the name and size of the
+// entry, as documents may come from another server. Otherwise the item is
+// looked up by name, size and shape.
+func matchDocumentFurniture(f roomdoc.Furniture, byID map[int64]*data.Furniture, byName map[string][]*data.Furniture, userID int64) *data.Furniture {
+	fits := func(item *data.Furniture) bool {
+		shape := roomdoc.ShapeRectangle
+		if item.Shape == data.Circle {
+			shape = roomdoc.ShapeCircle
+		}
+
+		return strings.EqualFold(item.Name, f.Name) && item.Width == f.Width && item.Height == f.Height &&
+			(f.Shape == "" || f.Shape == shape)
+	}
+
+	if item, ok := byID[f.CatalogID]; ok && item.ArchivedAt == nil && item.VisibleTo(userID) {
+		if !f.HasSpec() || fits(item) {
+			return item
+		}
+	}
+
+	if !f.HasSpec() {
+		return nil
+	}
+
+	for _, item := range byName[strings.ToLower(f.Name)] {
+		if fits(item) {
+			return item
+		}
+	}
+
+	return nil
+}
@@ -11,10 +11,11 @@ import (
 
 func (app *application) createRoomHandler(w http.ResponseWriter, r *http.Request) {
 	type furnitureInput struct {
-		FurnitureID int64 `json:"furniture_id"`
-		RoomID      int64 `json:"-"`
-		X           int64 `json:"x"`
-		Y           int64 `json:"y"`
+		FurnitureID int64   `json:"furniture_id"`
+		RoomID      int64   `json:"-"`
+		X           int64   `json:"x"`
+		Y           int64   `json:"y"`
+		Rotation    float64 `json:"rotation"`
 	}
 
 	var input struct {
@@ -22,7 +23,10 @@ func (app *application) createRoomHandler(w http.ResponseWriter, r *http.Request
 		Title         string           `json:"title"`
 		Width         int64            `json:"width"`
 		Height        int64            `json:"height"`
+		Visibility    string           `json:"visibility"`
+		Budget        *data.Money      `json:"budget"`
 		FurnitureList []furnitureInput `json:"furniture_list"`
+		AllowOverlap  bool             `json:"allow_overlap"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -31,24 +35,13 @@ func (app *application) createRoomHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	furnitureIDs, err := app.models.Furniture.GetAllID()
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
 	var furnitureList []data.FurnitureList
 	for _, val := range input.FurnitureList {
-		if !validator.InInts(val.FurnitureID, furnitureIDs...) {
-			err = errors.New("no furniture with this id")
-			app.badRequestResponse(w, r, err)
-			return
-		}
-
 		furnitureList = append(furnitureList, data.FurnitureList{
 			FurnitureID: val.FurnitureID,
 			X:           val.X,
 			Y:           val.Y,
+			Rotation:    val.Rotation,
 		})
 	}
 
@@ -64,9 +57,15 @@ func (app *application) createRoomHandler(w http.ResponseWriter, r *http.Request
 		Title:         input.Title,
 		Width:         input.Width,
 		Height:        input.Height,
+		Visibility:    input.Visibility,
+		Budget:        input.Budget,
 		FurnitureList: furnitureList,
 	}
 
+	if room.Visibility == "" {
+		room.Visibility = data.VisibilityPrivate
+	}
+
 	v := validator.New()
 
 	if data.ValidateRoom(v, room); !v.Valid() {
@@ -74,25 +73,68 @@ func (app *application) createRoomHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Room.Insert(room)
+	err = app.validateFurnitureIDs(v, room.OwnerID, furnitureList)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	for key := range furnitureList {
-		furnitureList[key].RoomID = room.ID
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	for _, val := range furnitureList {
+		if data.ValidateFurnitureList(v, &val); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
 	}
 
-	err = app.models.FurnitureList.InsertTransaction(furnitureList)
+	err = app.validateFurnitureLayout(v, room, nil, input.AllowOverlap)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		err := tx.Room.Insert(room)
+		if err != nil {
+			return err
+		}
+
+		for key := range room.FurnitureList {
+			room.FurnitureList[key].RoomID = room.ID
+		}
+
+		err = tx.FurnitureList.InsertAll(room.FurnitureList)
+		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	budgetStatus, err := app.budgetStatus(room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/rooms/%d", room.ID))
+	headers.Set("ETag", versionETag(room.Version))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"room": room}, headers)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"room": room, "budget_status": budgetStatus}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -117,6 +159,18 @@ func (app *application) showRoomHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Don't reveal that a private room exists to anybody who can't see it.
+	canView, err := app.canViewRoom(app.contextGetUser(r), room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !canView {
+		app.notFoundResponse(w, r)
+		return
+	}
+
 	furnitureList, err := app.models.FurnitureList.GetAll(room.ID)
 	if err != nil {
 		switch {
@@ -130,7 +184,10 @@ func (app *application) showRoomHandler(w http.ResponseWriter, r *http.Request)
 
 	room.FurnitureList = furnitureList
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"room": room}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"room": room}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -160,12 +217,23 @@ func (app *application) updateRoomHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if room.OwnerID != user.ID {
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !data.RoleCanEdit(role) {
 		app.foreignRoomResponse(w, r)
 		return
 	}
 
-	furnitureList, err := app.models.FurnitureList.GetAll(room.ID)
+	if !app.ifMatch(r, room.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	previous, err := app.models.FurnitureList.GetAll(room.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -176,13 +244,15 @@ func (app *application) updateRoomHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	room.FurnitureList = furnitureList
+	room.FurnitureList = previous
 
 	type furnitureInput struct {
-		FurnitureID int64 `json:"furniture_id"`
-		RoomID      int64 `json:"room_id"`
-		X           int64 `json:"x"`
-		Y           int64 `json:"y"`
+		ID          int64   `json:"id"` // Existing placement to keep, 0 for a new one
+		FurnitureID int64   `json:"furniture_id"`
+		RoomID      int64   `json:"room_id"`
+		X           int64   `json:"x"`
+		Y           int64   `json:"y"`
+		Rotation    float64 `json:"rotation"`
 	}
 
 	var input struct {
@@ -190,7 +260,10 @@ func (app *application) updateRoomHandler(w http.ResponseWriter, r *http.Request
 		Title         *string          `json:"title"`
 		Width         *int64           `json:"width"`
 		Height        *int64           `json:"height"`
+		Visibility    *string          `json:"visibility"`
+		Budget        nullableMoney    `json:"budget"`
 		FurnitureList []furnitureInput `json:"furniture_list"`
+		AllowOverlap  bool             `json:"allow_overlap"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -215,6 +288,35 @@ func (app *application) updateRoomHandler(w http.ResponseWriter, r *http.Request
 		room.Height = *input.Height
 	}
 
+	if input.Visibility != nil {
+		// Making a room public is up to the people managing it.
+		if *input.Visibility != room.Visibility && !data.RoleCanManage(role) {
+			app.foreignRoomResponse(w, r)
+			return
+		}
+		room.Visibility = *input.Visibility
+	}
+
+	if input.Budget.Set {
+		room.Budget = input.Budget.Value
+	}
+
+	if input.FurnitureList != nil {
+		var furnitureList []data.FurnitureList
+		for _, val := range input.FurnitureList {
+			furnitureList = append(furnitureList, data.FurnitureList{
+				ID:          val.ID,
+				FurnitureID: val.FurnitureID,
+				RoomID:      room.ID,
+				X:           val.X,
+				Y:           val.Y,
+				Rotation:    val.Rotation,
+			})
+		}
+
+		room.FurnitureList = furnitureList
+	}
+
 	v := validator.New()
 
 	if data.ValidateRoom(v, room); !v.Valid() {
@@ -223,50 +325,75 @@ func (app *application) updateRoomHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	if input.FurnitureList != nil {
-		furnitureIDs, err := app.models.Furniture.GetAllID()
+		data.ValidatePlacementIDs(v, room.FurnitureList, previous)
+
+		err = app.validateFurnitureIDs(v, room.OwnerID, room.FurnitureList)
 		if err != nil {
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 
-		var furnitureList []data.FurnitureList
-		for _, val := range input.FurnitureList {
-			if !validator.InInts(val.FurnitureID, furnitureIDs...) {
-				err = errors.New("no furniture with this id")
-				app.badRequestResponse(w, r, err)
-				return
-			}
-
-			furnitureList = append(furnitureList, data.FurnitureList{
-				FurnitureID: val.FurnitureID,
-				RoomID:      room.ID,
-				X:           val.X,
-				Y:           val.Y,
-			})
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
 		}
 
-		for _, val := range furnitureList {
+		for _, val := range room.FurnitureList {
 			if data.ValidateFurnitureList(v, &val); !v.Valid() {
 				app.failedValidationResponse(w, r, v.Errors)
 				return
 			}
 		}
+	}
 
-		room.FurnitureList = furnitureList
-		app.models.FurnitureList.Delete(room.ID)
-		err = app.models.FurnitureList.InsertTransaction(furnitureList)
+	// Existing placements were already checked against each other when they
+	// were saved, but a smaller room may no longer be able to hold them.
+	err = app.validateFurnitureLayout(v, room, previous, input.AllowOverlap || input.FurnitureList == nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		if input.FurnitureList != nil {
+			err := tx.FurnitureList.ReplaceAll(room.ID, room.FurnitureList)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Room.Update(room)
 		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
 			app.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
-	err = app.models.Room.Update(room)
+	budgetStatus, err := app.budgetStatus(room)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"room": room}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"room": room, "budget_status": budgetStatus}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -296,16 +423,29 @@ func (app *application) deleteRoomHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if room.OwnerID != user.ID {
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !data.RoleCanManage(role) {
 		app.foreignRoomResponse(w, r)
 		return
 	}
 
-	err = app.models.Room.Delete(id)
+	if !app.ifMatch(r, room.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
+	err = app.models.Room.Delete(id, room.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -320,17 +460,21 @@ func (app *application) deleteRoomHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) listRoomHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string `json:"title"`
-		Width  int    `json:"width"`
-		Height int    `json:"height"`
+		Title      string `json:"title"`
+		Width      int    `json:"width"`
+		Height     int    `json:"height"`
+		Visibility string `json:"visibility"`
+		OverBudget bool   `json:"over_budget"`
 		data.Filters
 	}
 	v := validator.New()
 	qs := r.URL.Query()
 
 	input.Title = app.readString(qs, "title", "")
+	input.Visibility = app.readString(qs, "visibility", "")
 	input.Width = app.readInt(qs, "width", 0, v)
 	input.Height = app.readInt(qs, "height", 0, v)
+	input.OverBudget = app.readBool(qs, "over_budget", false, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
@@ -338,31 +482,53 @@ func (app *application) listRoomHandler(w http.ResponseWriter, r *http.Request)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
 
 	input.Filters.SortSafelist = []string{"id", "title", "room_width", "room_height", "-id", "-title", "-room_width", "-room_height"}
+	input.Filters.SortColumns = map[string]string{"id": "room_id"}
+
+	v.Check(input.Visibility == "" || validator.In(input.Visibility, data.VisibilityPrivate, data.VisibilityUnlisted, data.VisibilityPublic),
+		"visibility", "must be one of private, unlisted or public")
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	rooms, metadata, err := app.models.Room.GetAll(input.Title, input.Width, input.Height, input.Filters)
+	// Public rooms of all users can be browsed, otherwise only the rooms the
+	// caller owns or collaborates on are listed.
+	userID := app.contextGetUser(r).ID
+	if input.Visibility == data.VisibilityPublic {
+		userID = 0
+	}
+
+	rooms, metadata, err := app.models.Room.GetAll(userID, input.Visibility, input.Title, input.Width, input.Height, input.OverBudget, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	ids := make([]int64, 0, len(rooms))
 	for _, val := range rooms {
-		furnitureList, err := app.models.FurnitureList.GetAll(val.ID)
-		if err != nil {
-			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
-		}
+		ids = append(ids, val.ID)
+	}
 
-		val.FurnitureList = furnitureList
+	furnitureLists, err := app.models.FurnitureList.GetAllForRooms(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	changes, err := app.models.FurnitureChanges.GetLatestForRooms(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, val := range rooms {
+		val.FurnitureList = furnitureLists[val.ID]
+
+		// The version makes the URL change along with the room and its
+		// furniture, so clients may cache the image for as long as they like.
+		val.ThumbnailURL = fmt.Sprintf("/v1/rooms/%d/plan.png?width=%d&v=%s", val.ID, thumbnailWidth,
+			thumbnailVersion(val.Version, changes[val.ID]))
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"rooms": rooms, "metadata": metadata}, nil)
@@ -371,3 +537,138 @@ func (app *application) listRoomHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 }
+
+// roomRole returns the role the user has in the room: owner, one of the
+// collaborator roles, or "" if the user has no access of their own.
+func (app *application) roomRole(user *data.User, room *data.Room) (string, error) {
+	if user.IsAnonymous() {
+		return "", nil
+	}
+
+	if room.OwnerID == user.ID {
+		return data.RoleOwner, nil
+	}
+
+	role, err := app.models.Collaborators.GetRole(room.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return "", nil
+		default:
+			return "", err
+		}
+	}
+
+	return role, nil
+}
+
+// canViewRoom reports whether the user may see the room and its furniture.
+// Unlisted rooms are visible to anybody who knows their ID.
+func (app *application) canViewRoom(user *data.User, room *data.Room) (bool, error) {
+	if room.Visibility != data.VisibilityPrivate {
+		return true, nil
+	}
+
+	role, err := app.roomRole(user, room)
+	if err != nil {
+		return false, err
+	}
+
+	return role != "", nil
+}
+
+// budgetStatus prices the current furniture list of the room against its
+// budget.
+func (app *application) budgetStatus(room *data.Room) (*data.BudgetStatus, error) {
+	ids := make([]int64, 0, len(room.FurnitureList))
+	for _, val := range room.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	furniture, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.NewBudgetStatus(room, furniture)
+}
+
+// nullableMoney tells a JSON null, which clears an amount, apart from a field
+// that was left out of the request body.
+type nullableMoney struct {
+	Set   bool
+	Value *data.Money
+}
+
+func (n *nullableMoney) UnmarshalJSON(jsonValue []byte) error {
+	n.Set = true
+
+	if string(jsonValue) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	n.Value = new(data.Money)
+	return n.Value.UnmarshalJSON(jsonValue)
+}
+
+// validateFurnitureIDs checks that every placement refers to an existing
+// catalog item, or to a custom item of the room owner.
+func (app *application) validateFurnitureIDs(v *validator.Validator, ownerID int64, flist []data.FurnitureList) error {
+	if len(flist) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(flist))
+	for _, val := range flist {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	missing, err := app.models.Furniture.GetMissingIDs(ids, ownerID)
+	if err != nil {
+		return err
+	}
+
+	data.ValidateFurnitureIDs(v, flist, missing)
+	return nil
+}
+
+// validateFurnitureLayout checks that every placement of the room fits inside
+// the room boundaries and, unless the client explicitly allowed overlapping
+// furniture, that no two placements overlap. Discontinued catalog items may
+// only be used if they were already part of the previous layout.
+func (app *application) validateFurnitureLayout(v *validator.Validator, room *data.Room, previous []data.FurnitureList, allowOverlap bool) error {
+	if len(room.FurnitureList) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(room.FurnitureList))
+	for _, val := range room.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	furniture, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	checkFurnitureLayout(v, room, previous, furniture, allowOverlap)
+	return nil
+}
+
+// checkFurnitureLayout runs the checks of validateFurnitureLayout against
+// furniture which has already been loaded.
+func checkFurnitureLayout(v *validator.Validator, room *data.Room, previous []data.FurnitureList, furniture map[int64]*data.Furniture, allowOverlap bool) {
+	for i, val := range room.FurnitureList {
+		if f, ok := furniture[val.FurnitureID]; ok {
+			room.FurnitureList[i].SetFootprint(f)
+		}
+	}
+
+	data.ValidateArchivedFurniture(v, room.FurnitureList, previous, furniture)
+	data.ValidateFurnitureBounds(v, room, furniture)
+
+	if !allowOverlap {
+		data.ValidateFurnitureCollisions(v, room.FurnitureList, furniture)
+	}
+}
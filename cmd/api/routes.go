@@ -17,15 +17,52 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.requirePermission("admin", app.healthcheckHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/rooms", app.requireAuthenticatedUser(app.listRoomHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/rooms", app.requirePermission("user", app.createRoomHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rooms/:id", app.requirePermission("user", app.postRoomHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id", app.requirePermission("user", app.showRoomHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/rooms/:id", app.requirePermission("user", app.updateRoomHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/rooms/:id", app.requirePermission("user", app.deleteRoomHandler))
 
+	router.HandlerFunc(http.MethodPost, "/v1/rooms/:id/furniture", app.requirePermission("user", app.createPlacementHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/rooms/:id/furniture/:placement_id", app.requirePermission("user", app.updatePlacementHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/rooms/:id/furniture/:placement_id", app.requirePermission("user", app.deletePlacementHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/bill-of-materials", app.requirePermission("user", app.showBillOfMaterialsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/plan.svg", app.requirePermission("user", app.showRoomPlanSVGHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/plan.png", app.requirePermission("user", app.showRoomPlanPNGHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/plan.pdf", app.requirePermission("user", app.showRoomPlanPDFHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/plan.dxf", app.requirePermission("user", app.showRoomPlanDXFHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/plan.sh3d", app.requirePermission("user", app.showRoomPlanSH3DHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/export", app.requirePermission("user", app.exportRoomHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rooms/:id/clone", app.requirePermission("user", app.cloneRoomHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/compare/:other_id", app.requirePermission("user", app.compareRoomsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/revisions", app.requirePermission("user", app.listRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/revisions/:revision_id", app.requirePermission("user", app.showRevisionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rooms/:id/revisions/:revision_id/restore", app.requirePermission("user", app.restoreRevisionHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/collaborators", app.requirePermission("user", app.listCollaboratorsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rooms/:id/collaborators", app.requirePermission("user", app.createCollaboratorHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/rooms/:id/collaborators/:user_id", app.requirePermission("user", app.deleteCollaboratorHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/rooms/:id/share-links", app.requirePermission("user", app.listShareLinksHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rooms/:id/share-links", app.requirePermission("user", app.createShareLinkHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/rooms/:id/share-links/:link_id", app.requirePermission("user", app.revokeShareLinkHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/shared/:token", app.showSharedRoomHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/templates", app.requirePermission("user", app.listTemplatesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/templates", app.requirePermission("admin", app.createTemplateHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/templates/:id", app.requirePermission("admin", app.deleteTemplateHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/templates/:id/rooms", app.requirePermission("user", app.createRoomFromTemplateHandler))
+
+	catalogEditors := []string{"admin", "catalog:write"}
+
 	router.HandlerFunc(http.MethodGet, "/v1/furniture", app.listFurnitureHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/furniture", app.createFurnitureHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/furniture", app.requireAnyPermission(catalogEditors, app.createFurnitureHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/furniture/:id", app.showFurnitureHandler)
-	router.HandlerFunc(http.MethodPatch, "/v1/furniture/:id", app.updateFurnitureHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/furniture/:id", app.deleteFurnitureHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/furniture/:id", app.requireAnyPermission(catalogEditors, app.updateFurnitureHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/furniture/:id", app.requireAnyPermission(catalogEditors, app.deleteFurnitureHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/furniture/:id/restore", app.requireAnyPermission(catalogEditors, app.restoreFurnitureHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/furniture/:id/changes", app.requireAnyPermission(catalogEditors, app.listFurnitureChangesHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/geometry"
+	"github.com/WrastAct/EHome/internal/sh3d"
+)
+
+const (
+	// pieceHeight is the height of exported pieces, as the catalog doesn't
+	// know how tall its items are.
+	pieceHeight = 75
+
+	// wallThickness is the thickness of the walls put around an exported
+	// room.
+	wallThickness = 10
+
+	// sizeTolerance is how far, in centimetres, the size of a piece may be
+	// off from a catalog item of the same name for the two to match.
+	sizeTolerance = 1
+)
+
+func (app *application) showRoomPlanSH3DHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	furniture, err := app.roomFurniture(room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	err = sh3d.Write(&buf, roomSH3D(room, furniture))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", formatMediaTypes["sh3d"])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d.sh3d"`, room.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// roomSH3D converts the room into a home with a single room, walled in on
+// the outside so that the floor keeps its full size, and one box-shaped piece
+// per placement.
+func roomSH3D(room *data.Room, furniture map[int64]*data.Furniture) *sh3d.Home {
+	width, height := float64(room.Width), float64(room.Height)
+
+	home := &sh3d.Home{
+		Name: room.Title,
+		Rooms: []sh3d.Room{{
+			Name:   room.Title,
+			Points: geometry.Rect{Width: width, Height: height}.Polygon(),
+		}},
+	}
+
+	t := float64(wallThickness) / 2
+	corners := geometry.Rect{X: -t, Y: -t, Width: width + 2*t, Height: height + 2*t}.Polygon()
+
+	for i, a := range corners {
+		b := corners[(i+1)%len(corners)]
+		home.Walls = append(home.Walls, sh3d.Wall{
+			XStart:    a.X,
+			YStart:    a.Y,
+			XEnd:      b.X,
+			YEnd:      b.Y,
+			Thickness: wallThickness,
+		})
+	}
+
+	for _, val := range room.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		bounds := val.Footprint(f).Bounds()
+
+		price := ""
+		if f.Price > 0 {
+			price = f.Price.String()
+		}
+
+		home.Pieces = append(home.Pieces, sh3d.Piece{
+			Name:    f.Name,
+			X:       bounds.X + bounds.Width/2,
+			Y:       bounds.Y + bounds.Height/2,
+			Width:   float64(f.Width),
+			Depth:   float64(f.Height),
+			Height:  pieceHeight,
+			Angle:   val.Rotation,
+			Price:   price,
+			Visible: true,
+		})
+	}
+
+	return home
+}
+
+// sh3dArea is a part of a home which becomes a room.
+type sh3dArea struct {
+	name     string
+	level    string
+	anyLevel bool // Set for areas guessed from the whole home
+	outline  geometry.Polygon
+}
+
+func (a sh3dArea) contains(p sh3d.Piece) bool {
+	return (a.anyLevel || a.level == p.Level) && a.outline.Contains(geometry.Point{X: p.X, Y: p.Y})
+}
+
+// roomsFromSH3D turns every room of the home into a room with the pieces
+// standing in it. Homes without rooms become a single room spanning the
+// walls or, failing that, the furniture. Pieces are matched to the items
+// visible to the user by name and size; for the others private custom items
+// are created.
+func (app *application) roomsFromSH3D(home *sh3d.Home, title string, userID int64) (*roomImport, error) {
+	imp := &roomImport{furniture: map[int64]*data.Furniture{}}
+
+	areas := sh3dAreas(home)
+	if len(areas) == 0 {
+		return imp, nil
+	}
+
+	placed := make([][]sh3d.Piece, len(areas))
+	var names []string
+
+	for _, p := range home.Pieces {
+		switch {
+		case p.DoorOrWindow:
+			imp.issues = append(imp.issues, importIssue{Name: p.Name, Reason: "doors and windows are not imported"})
+			continue
+		case !p.Visible:
+			imp.issues = append(imp.issues, importIssue{Name: p.Name, Reason: "piece is hidden"})
+			continue
+		}
+
+		found := false
+		for i, area := range areas {
+			if area.contains(p) {
+				placed[i] = append(placed[i], p)
+				names = append(names, p.Name)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			imp.issues = append(imp.issues, importIssue{Name: p.Name, Reason: "piece doesn't stand in any room"})
+		}
+	}
+
+	var byName map[string][]*data.Furniture
+	if len(names) > 0 {
+		var err error
+		byName, err = app.models.Furniture.GetByNames(names, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	custom := map[string]*data.Furniture{}
+
+	for i, area := range areas {
+		bounds := area.outline.Bounds()
+
+		name := area.name
+		if name == "" {
+			name = title
+			if len(areas) > 1 {
+				name = fmt.Sprintf("%s %d", title, i+1)
+			}
+		}
+
+		room := &data.Room{
+			Title:  truncateName(name, 30),
+			Width:  int64(math.Round(bounds.Width)),
+			Height: int64(math.Round(bounds.Height)),
+		}
+
+		if math.Abs(area.outline.Area()-bounds.Width*bounds.Height) >= 1 {
+			imp.issues = append(imp.issues, importIssue{Name: room.Title, Reason: "room isn't rectangular, so its bounding box was used"})
+		}
+
+		for _, p := range placed[i] {
+			width, depth := int64(math.Round(p.Width)), int64(math.Round(p.Depth))
+
+			f := matchPiece(byName[strings.ToLower(p.Name)], width, depth)
+			if f == nil {
+				if width < 1 || depth < 1 || width >= 1000 || depth >= 1000 {
+					imp.issues = append(imp.issues, importIssue{Name: p.Name, Reason: "piece is too large or too small to become a custom item"})
+					continue
+				}
+
+				key := fmt.Sprintf("%s|%d|%d", strings.ToLower(p.Name), width, depth)
+
+				f = custom[key]
+				if f == nil {
+					f = newCustomFurniture(p, width, depth, userID)
+					f.ID = -int64(len(imp.custom) + 1)
+					custom[key] = f
+					imp.custom = append(imp.custom, f)
+				}
+			}
+
+			imp.furniture[f.ID] = f
+
+			placement := data.FurnitureList{
+				FurnitureID: f.ID,
+				Rotation:    normalizeRotation(p.Angle),
+			}
+
+			w, h := geometry.RotatedSize(float64(f.Width), float64(f.Height), placement.Rotation)
+			placement.X = roundCoordinate(p.X - bounds.X - w/2)
+			placement.Y = roundCoordinate(p.Y - bounds.Y - h/2)
+
+			room.FurnitureList = append(room.FurnitureList, placement)
+		}
+
+		imp.rooms = append(imp.rooms, room)
+	}
+
+	return imp, nil
+}
+
+// sh3dAreas lists the areas of the home which become rooms.
+func sh3dAreas(home *sh3d.Home) []sh3dArea {
+	var areas []sh3dArea
+
+	for _, room := range home.Rooms {
+		areas = append(areas, sh3dArea{name: room.Name, level: room.Level, outline: room.Points})
+	}
+
+	if len(areas) > 0 {
+		return areas
+	}
+
+	var outline geometry.Polygon
+
+	switch {
+	case len(home.Walls) > 0:
+		// The walls run along their centre lines, so the room ends half a
+		// wall further in.
+		var points geometry.Polygon
+		thickness := 0.0
+
+		for _, wall := range home.Walls {
+			points = append(points, geometry.Point{X: wall.XStart, Y: wall.YStart}, geometry.Point{X: wall.XEnd, Y: wall.YEnd})
+			thickness = math.Max(thickness, wall.Thickness)
+		}
+
+		b := points.Bounds()
+		t := thickness / 2
+		outline = geometry.Rect{X: b.X + t, Y: b.Y + t, Width: b.Width - 2*t, Height: b.Height - 2*t}.Polygon()
+
+	case len(home.Pieces) > 0:
+		var points geometry.Polygon
+
+		for _, p := range home.Pieces {
+			b := geometry.RotatedRect(p.X, p.Y, p.Width, p.Depth, p.Angle).Bounds()
+			points = append(points, geometry.Point{X: b.X, Y: b.Y}, geometry.Point{X: b.X + b.Width, Y: b.Y + b.Height})
+		}
+
+		outline = points.Bounds().Polygon()
+
+	default:
+		return nil
+	}
+
+	return []sh3dArea{{anyLevel: true, outline: outline}}
+}
+
+// matchPiece picks the first of the items named like a piece whose size
+// matches it.
+func matchPiece(candidates []*data.Furniture, width, depth int64) *data.Furniture {
+	for _, f := range candidates {
+		if abs(f.Width-width) <= sizeTolerance && abs(f.Height-depth) <= sizeTolerance {
+			return f
+		}
+	}
+	return nil
+}
+
+// newCustomFurniture creates a private item of the user for a piece which
+// doesn't match any item.
+func newCustomFurniture(p sh3d.Piece, width, depth int64, userID int64) *data.Furniture {
+	name := truncateName(p.Name, 40)
+	if name == "" {
+		name = "Custom item"
+	}
+
+	price, err := data.ParseMoney(p.Price)
+	if err != nil || price < 0 {
+		price = 0
+	}
+
+	return &data.Furniture{
+		Name:        name,
+		Price:       price,
+		Description: "Imported from Sweet Home 3D",
+		Width:       width,
+		Height:      depth,
+		Shape:       data.Rectangle,
+		OwnerID:     &userID,
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
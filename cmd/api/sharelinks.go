@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (app *application) createShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readManagedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ExpiryDays int `json:"expiry_days"`
+	}
+
+	// The body is optional, all fields have defaults.
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if input.ExpiryDays == 0 {
+		input.ExpiryDays = 7
+	}
+
+	v := validator.New()
+
+	v.Check(input.ExpiryDays > 0, "expiry_days", "must be greater than zero")
+	v.Check(input.ExpiryDays <= 365, "expiry_days", "must not be more than 365")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	link, err := app.models.ShareLinks.New(room.ID, user.ID, time.Duration(input.ExpiryDays)*24*time.Hour)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The token is only ever sent in this response, so it is kept out of the
+	// Location header, which ends up in logs and caches.
+	err = app.writeJSON(w, http.StatusCreated, envelope{"share_link": link}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listShareLinksHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := app.readManagedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := app.models.ShareLinks.GetActiveForRoom(room.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"share_links": links}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) revokeShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	linkID, err := app.readNamedIDParam(r, "link_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	room, ok := app.readManagedRoom(w, r)
+	if !ok {
+		return
+	}
+
+	err = app.models.ShareLinks.Revoke(room.ID, linkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "share link successfully revoked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showSharedRoomHandler(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	roomID, err := app.models.ShareLinks.GetRoomIDForToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	room, err := app.models.Room.Get(roomID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	furnitureList, err := app.models.FurnitureList.GetAll(room.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	room.FurnitureList = furnitureList
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"room": room}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readManagedRoom loads the room from the id URL parameter and checks that the
+// current user may manage who has access to it. If not, an error response has
+// already been sent and ok is false.
+func (app *application) readManagedRoom(w http.ResponseWriter, r *http.Request) (*data.Room, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	room, err := app.models.Room.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	role, err := app.roomRole(app.contextGetUser(r), room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if !data.RoleCanManage(role) {
+		app.foreignRoomResponse(w, r)
+		return nil, false
+	}
+
+	return room, true
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func (app *application) cloneRoomHandler(w http.ResponseWriter, r *http.Request) {
+	source, ok := app.readViewableRoom(w, r, "id")
+	if !ok {
+		return
+	}
+
+	app.createRoomFromSource(w, r, source)
+}
+
+func (app *application) listTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := app.models.Templates.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"templates": templates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RoomID      int64  `json:"room_id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	template := &data.RoomTemplate{
+		RoomID:      &input.RoomID,
+		Name:        input.Name,
+		Description: input.Description,
+		CreatedBy:   &user.ID,
+	}
+
+	v := validator.New()
+
+	if data.ValidateTemplate(v, template); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	room, err := app.models.Room.Get(input.RoomID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("room_id", fmt.Sprintf("no room with id %d", input.RoomID))
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Publishing makes the room available to everybody, so only rooms the
+	// admin can see already may be published. Others are reported as missing
+	// so as not to reveal that they exist.
+	canView, err := app.canViewRoom(user, room)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !canView {
+		v.AddError("room_id", fmt.Sprintf("no room with id %d", input.RoomID))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	room.FurnitureList, err = app.models.FurnitureList.GetAll(room.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	template.Room = room
+
+	err = app.models.Templates.Insert(template)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/templates/%d", template.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"template": template}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Templates.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "template successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createRoomFromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	template, err := app.models.Templates.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The snapshot taken when the template was published is available to
+	// everybody, whatever became of the room since.
+	source := template.Room
+	source.Title = template.Name
+
+	app.createRoomFromSource(w, r, source)
+}
+
+// createRoomFromSource copies the source room and its placements into a new
+// private room owned by the current user and sends it as the response. The
+// request body may set a different title for the copy. Custom items of other
+// users are copied into the account of the current user, as they couldn't be
+// used in the copy otherwise; placements of items which no longer exist are
+// reported as skipped.
+func (app *application) createRoomFromSource(w http.ResponseWriter, r *http.Request, source *data.Room) {
+	var input struct {
+		Title *string `json:"title"`
+	}
+
+	// The body is optional, the copy keeps the title of the source by default.
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	user := app.contextGetUser(r)
+
+	room := &data.Room{
+		OwnerID:     user.ID,
+		Description: source.Description,
+		Title:       source.Title,
+		Width:       source.Width,
+		Height:      source.Height,
+		Visibility:  data.VisibilityPrivate,
+		Budget:      source.Budget,
+	}
+
+	if input.Title != nil {
+		room.Title = *input.Title
+	}
+
+	v := validator.New()
+
+	if data.ValidateRoom(v, room); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ids := make([]int64, 0, len(source.FurnitureList))
+	for _, val := range source.FurnitureList {
+		ids = append(ids, val.FurnitureID)
+	}
+
+	furniture, err := app.models.Furniture.GetByIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	copies := map[int64]*data.Furniture{}
+	custom := []*data.Furniture{}
+	issues := []importIssue{}
+
+	// The layout was valid in the source room, so it is copied as it is,
+	// including discontinued furniture.
+	for _, val := range source.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			// Custom items go away with the account of their owner, which
+			// template snapshots don't prevent.
+			issues = append(issues, importIssue{
+				Name:   fmt.Sprintf("furniture %d", val.FurnitureID),
+				Reason: "furniture no longer exists",
+			})
+			continue
+		}
+
+		if !f.VisibleTo(user.ID) {
+			if _, ok := copies[f.ID]; !ok {
+				c := *f
+				c.OwnerID = &user.ID
+				c.ArchivedAt = nil
+				copies[f.ID] = &c
+				custom = append(custom, &c)
+			}
+		}
+
+		val.ID = 0
+		room.FurnitureList = append(room.FurnitureList, val)
+	}
+
+	err = app.models.Transaction(func(tx data.Models) error {
+		for _, c := range custom {
+			err := tx.Furniture.Insert(c)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Room.Insert(room)
+		if err != nil {
+			return err
+		}
+
+		for key, val := range room.FurnitureList {
+			room.FurnitureList[key].RoomID = room.ID
+			if c, ok := copies[val.FurnitureID]; ok {
+				room.FurnitureList[key].FurnitureID = c.ID
+			}
+		}
+
+		err = tx.FurnitureList.InsertAll(room.FurnitureList)
+		if err != nil {
+			return err
+		}
+
+		return app.recordRevision(tx, room, user)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/rooms/%d", room.ID))
+	headers.Set("ETag", versionETag(room.Version))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"room": room, "custom_furniture": custom, "skipped": issues}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/WrastAct/EHome/internal/render"
+)
+
+// thumbnailWidth is the width of the previews linked from the room listing.
+// Renderings up to thumbnailMaxWidth pixels wide are kept in the cache.
+const (
+	thumbnailWidth    = 320
+	thumbnailMaxWidth = 512
+)
+
+// thumbnailKey includes the latest change to the catalog items of the room,
+// as editing an item changes the drawing without changing the room version.
+type thumbnailKey struct {
+	roomID          int64
+	furnitureChange int64
+	width           int
+	opts            render.Options
+}
+
+type thumbnailEntry struct {
+	key     thumbnailKey
+	version int
+	png     []byte
+}
+
+// thumbnailCache keeps the most recently used PNG renderings of rooms. Every
+// entry remembers the room version it was drawn from, so a room which changed
+// since is simply a cache miss and gets drawn again.
+type thumbnailCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[thumbnailKey]*list.Element
+}
+
+// thumbnailVersion identifies everything a thumbnail is drawn from: the room
+// itself and the catalog items placed in it.
+func thumbnailVersion(roomVersion int, furnitureChange int64) string {
+	return fmt.Sprintf("%d.%d", roomVersion, furnitureChange)
+}
+
+func newThumbnailCache(maxEntries int) *thumbnailCache {
+	return &thumbnailCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[thumbnailKey]*list.Element),
+	}
+}
+
+func (c *thumbnailCache) get(key thumbnailKey, version int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*thumbnailEntry)
+	if entry.version != version {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.png, true
+}
+
+func (c *thumbnailCache) put(key thumbnailKey, version int, png []byte) {
+	if c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value = &thumbnailEntry{key: key, version: version, png: png}
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&thumbnailEntry{key: key, version: version, png: png})
+
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*thumbnailEntry).key)
+	}
+}
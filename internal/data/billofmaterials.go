@@ -0,0 +1,76 @@
+package data
+
+import "sort"
+
+type BillOfMaterialsLine struct {
+	FurnitureID  int64  `json:"furniture_id"`
+	Name         string `json:"name"`
+	Quantity     int64  `json:"quantity"`
+	UnitPrice    Money  `json:"unit_price"`
+	Total        Money  `json:"total"`
+	Discontinued bool   `json:"discontinued,omitempty"`
+}
+
+type BillOfMaterials struct {
+	RoomID   int64                 `json:"room_id"`
+	Lines    []BillOfMaterialsLine `json:"lines"`
+	Quantity int64                 `json:"quantity"` // Number of placed items
+	Total    Money                 `json:"total"`
+}
+
+// NewBillOfMaterials groups the placements of the room by furniture item and
+// totals their catalog prices. Lines are ordered by furniture name.
+func NewBillOfMaterials(room *Room, furniture map[int64]*Furniture) (*BillOfMaterials, error) {
+	bom := &BillOfMaterials{
+		RoomID: room.ID,
+		Lines:  []BillOfMaterialsLine{},
+	}
+
+	lines := map[int64]*BillOfMaterialsLine{}
+
+	for _, val := range room.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		line, ok := lines[f.ID]
+		if !ok {
+			line = &BillOfMaterialsLine{
+				FurnitureID:  f.ID,
+				Name:         f.Name,
+				UnitPrice:    f.Price,
+				Discontinued: f.ArchivedAt != nil,
+			}
+			lines[f.ID] = line
+		}
+
+		line.Quantity++
+	}
+
+	for _, line := range lines {
+		var err error
+
+		line.Total, err = line.UnitPrice.Mul(line.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		bom.Total, err = bom.Total.Add(line.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		bom.Quantity += line.Quantity
+		bom.Lines = append(bom.Lines, *line)
+	}
+
+	sort.Slice(bom.Lines, func(i, j int) bool {
+		if bom.Lines[i].Name != bom.Lines[j].Name {
+			return bom.Lines[i].Name < bom.Lines[j].Name
+		}
+		return bom.Lines[i].FurnitureID < bom.Lines[j].FurnitureID
+	})
+
+	return bom, nil
+}
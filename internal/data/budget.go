@@ -0,0 +1,53 @@
+package data
+
+// BudgetStatus compares the price of the furniture in a room with its budget.
+// Remaining is negative once the room is over budget.
+type BudgetStatus struct {
+	Budget     *Money `json:"budget"`
+	Spend      Money  `json:"spend"`
+	Remaining  *Money `json:"remaining"`
+	OverBudget bool   `json:"over_budget"`
+}
+
+func NewBudgetStatus(room *Room, furniture map[int64]*Furniture) (*BudgetStatus, error) {
+	spend, err := layoutPrice(room.FurnitureList, furniture)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BudgetStatus{
+		Budget: room.Budget,
+		Spend:  spend,
+	}
+
+	if room.Budget != nil {
+		// Both amounts are never negative, so the difference can't overflow.
+		remaining := *room.Budget - status.Spend
+		status.Remaining = &remaining
+		status.OverBudget = remaining < 0
+	}
+
+	return status, nil
+}
+
+// layoutPrice sums up the catalog price of every placement.
+func layoutPrice(flist []FurnitureList, furniture map[int64]*Furniture) (Money, error) {
+	var total Money
+	for _, val := range flist {
+		if f, ok := furniture[val.FurnitureID]; ok {
+			var err error
+			total, err = total.Add(f.Price)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return total, nil
+}
+
+func equalMoney(a, b *Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
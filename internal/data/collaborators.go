@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+const (
+	RoleOwner   = "owner" // Implied by Room.OwnerID, never stored
+	RoleCoOwner = "co-owner"
+	RoleEditor  = "editor"
+	RoleViewer  = "viewer"
+)
+
+// RoleCanEdit reports whether the role allows changing the room and its
+// furniture.
+func RoleCanEdit(role string) bool {
+	return role == RoleOwner || role == RoleCoOwner || role == RoleEditor
+}
+
+// RoleCanManage reports whether the role allows deleting the room and deciding
+// who else has access to it.
+func RoleCanManage(role string) bool {
+	return role == RoleOwner || role == RoleCoOwner
+}
+
+type Collaborator struct {
+	RoomID    int64     `json:"room_id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy *int64    `json:"invited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ValidateCollaborator(v *validator.Validator, collaborator *Collaborator) {
+	v.Check(validator.In(collaborator.Role, RoleViewer, RoleEditor, RoleCoOwner),
+		"role", "must be one of viewer, editor or co-owner")
+}
+
+type CollaboratorModel struct {
+	DB DBTX
+}
+
+// Insert adds the collaborator to the room, or changes their role if they
+// were already invited.
+func (m CollaboratorModel) Insert(collaborator *Collaborator) error {
+	query := `
+		INSERT INTO room_collaborators (room_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (room_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at`
+
+	args := []interface{}{collaborator.RoomID, collaborator.UserID, collaborator.Role, collaborator.InvitedBy}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&collaborator.CreatedAt)
+}
+
+// GetRole returns the role of the user in the room. ErrRecordNotFound is
+// returned if the user isn't a collaborator.
+func (m CollaboratorModel) GetRole(roomID, userID int64) (string, error) {
+	query := `
+		SELECT role
+		FROM room_collaborators
+		WHERE room_id = $1 AND user_id = $2`
+
+	var role string
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, roomID, userID).Scan(&role)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return role, nil
+}
+
+func (m CollaboratorModel) GetAllForRoom(roomID int64) ([]*Collaborator, error) {
+	query := `
+		SELECT room_collaborators.room_id, users.id, users.name, users.email,
+			room_collaborators.role, room_collaborators.invited_by, room_collaborators.created_at
+		FROM room_collaborators
+		INNER JOIN users ON users.id = room_collaborators.user_id
+		WHERE room_collaborators.room_id = $1
+		ORDER BY room_collaborators.created_at, users.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	collaborators := []*Collaborator{}
+
+	for rows.Next() {
+
+		var collaborator Collaborator
+
+		err := rows.Scan(
+			&collaborator.RoomID,
+			&collaborator.UserID,
+			&collaborator.Name,
+			&collaborator.Email,
+			&collaborator.Role,
+			&collaborator.InvitedBy,
+			&collaborator.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		collaborators = append(collaborators, &collaborator)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collaborators, nil
+}
+
+func (m CollaboratorModel) Delete(roomID, userID int64) error {
+	if roomID < 1 || userID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM room_collaborators
+		WHERE room_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, roomID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	SortColumns  map[string]string // SQL expressions for sort values which aren't column names
 }
 
 type Metadata struct {
@@ -25,7 +26,11 @@ type Metadata struct {
 func (f Filters) sortColumn() string {
 	for _, safeValue := range f.SortSafelist {
 		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+			column := strings.TrimPrefix(f.Sort, "-")
+			if expression, ok := f.SortColumns[column]; ok {
+				return expression
+			}
+			return column
 		}
 	}
 	panic("unsafe sort parameter: " + f.Sort)
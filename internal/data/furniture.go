@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/WrastAct/EHome/internal/validator"
+
+	"github.com/lib/pq"
 )
 
 type Shape int
@@ -17,14 +21,23 @@ const (
 )
 
 type Furniture struct {
-	ID          int64   `json:"id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description,omitempty"`
-	Width       int64   `json:"width"`
-	Height      int64   `json:"height"`
-	Image       string  `json:"image,omitempty"` // Path to the image
-	Shape       Shape   `json:"shape"`           // To improve collision detection
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Price       Money      `json:"price"`
+	Description string     `json:"description,omitempty"`
+	Width       int64      `json:"width"`
+	Height      int64      `json:"height"`
+	Image       string     `json:"image,omitempty"`       // Path to the image
+	Shape       Shape      `json:"shape"`                 // To improve collision detection
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"` // Set once the item is discontinued
+	OwnerID     *int64     `json:"owner_id,omitempty"`    // Set for private custom items
+}
+
+// VisibleTo reports whether the item may be seen and placed by the user. Items
+// in the shared catalog are visible to everyone, custom items only to their
+// owner.
+func (f *Furniture) VisibleTo(userID int64) bool {
+	return f.OwnerID == nil || *f.OwnerID == userID
 }
 
 func ValidateFurniture(v *validator.Validator, furniture *Furniture) {
@@ -51,14 +64,14 @@ func ValidateFurniture(v *validator.Validator, furniture *Furniture) {
 }
 
 type FurnitureModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func (f FurnitureModel) Insert(furniture *Furniture) error {
 	query := `
 		INSERT INTO furniture (name, price, furniture_description, 
-			furniture_width, furniture_height, image, shape)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+			furniture_width, furniture_height, image, shape, owner_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING furniture_id`
 
 	args := []interface{}{
@@ -69,6 +82,7 @@ func (f FurnitureModel) Insert(furniture *Furniture) error {
 		furniture.Height,
 		furniture.Image,
 		furniture.Shape,
+		furniture.OwnerID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -84,7 +98,7 @@ func (f FurnitureModel) Get(id int64) (*Furniture, error) {
 
 	query := `
 		SELECT furniture_id, name, price, furniture_description,
-			furniture_width, furniture_height, image, shape
+			furniture_width, furniture_height, image, shape, archived_at, owner_id
 		FROM furniture
 		WHERE furniture_id = $1`
 
@@ -102,6 +116,8 @@ func (f FurnitureModel) Get(id int64) (*Furniture, error) {
 		&furniture.Height,
 		&furniture.Image,
 		&furniture.Shape,
+		&furniture.ArchivedAt,
+		&furniture.OwnerID,
 	)
 
 	if err != nil {
@@ -116,23 +132,91 @@ func (f FurnitureModel) Get(id int64) (*Furniture, error) {
 	return &furniture, nil
 }
 
-func (f FurnitureModel) GetAll() ([]*Furniture, error) {
+// GetAll lists the catalog together with the custom items of the user.
+func (f FurnitureModel) GetAll(userID int64, name string, minPrice, maxPrice Money, maxWidth, maxHeight int, shape Shape, filters Filters) ([]*Furniture, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), furniture_id, name, price, furniture_description,
+			furniture_width, furniture_height, image, shape, archived_at, owner_id
+		FROM furniture
+		WHERE archived_at IS NULL
+		AND (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		AND (price >= $2 OR $2 = 0)
+		AND (price <= $3 OR $3 = 0)
+		AND (furniture_width <= $4 OR $4 = 0)
+		AND (furniture_height <= $5 OR $5 = 0)
+		AND (shape = $6 OR $6 = -1)
+		AND (owner_id IS NULL OR owner_id = $9)
+		ORDER BY %s %s, furniture_id ASC
+		LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{name, minPrice, maxPrice, maxWidth, maxHeight, shape, filters.limit(), filters.offset(), userID}
+
+	rows, err := f.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	furnitures := []*Furniture{} // I know it is uncountable
+
+	for rows.Next() {
+
+		var furniture Furniture
+
+		err := rows.Scan(
+			&totalRecords,
+			&furniture.ID,
+			&furniture.Name,
+			&furniture.Price,
+			&furniture.Description,
+			&furniture.Width,
+			&furniture.Height,
+			&furniture.Image,
+			&furniture.Shape,
+			&furniture.ArchivedAt,
+			&furniture.OwnerID,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		furnitures = append(furnitures, &furniture)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return furnitures, metadata, nil
+}
+
+// GetByIDs returns the catalog items with the given IDs, keyed by ID. IDs which
+// don't exist are left out of the map.
+func (f FurnitureModel) GetByIDs(ids []int64) (map[int64]*Furniture, error) {
 	query := `
 		SELECT furniture_id, name, price, furniture_description,
-			furniture_width, furniture_height, image, shape
-		FROM furniture`
+			furniture_width, furniture_height, image, shape, archived_at, owner_id
+		FROM furniture
+		WHERE furniture_id = ANY($1)`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := f.DB.QueryContext(ctx, query)
+	rows, err := f.DB.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	furnitures := []*Furniture{} // I know it is uncountable
+	furnitures := make(map[int64]*Furniture)
 
 	for rows.Next() {
 
@@ -147,12 +231,14 @@ func (f FurnitureModel) GetAll() ([]*Furniture, error) {
 			&furniture.Height,
 			&furniture.Image,
 			&furniture.Shape,
+			&furniture.ArchivedAt,
+			&furniture.OwnerID,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		furnitures = append(furnitures, &furniture)
+		furnitures[furniture.ID] = &furniture
 	}
 
 	if err = rows.Err(); err != nil {
@@ -162,20 +248,86 @@ func (f FurnitureModel) GetAll() ([]*Furniture, error) {
 	return furnitures, nil
 }
 
-func (f FurnitureModel) GetAllID() ([]int64, error) {
-	query := `SELECT furniture_id FROM furniture`
+// GetByNames returns the items with the given names that are visible to the
+// user, ignoring case and discontinued items, keyed by lower-cased name. Items
+// sharing a name are listed catalog items first, then oldest first.
+func (f FurnitureModel) GetByNames(names []string, userID int64) (map[string][]*Furniture, error) {
+	query := `
+		SELECT furniture_id, name, price, furniture_description,
+			furniture_width, furniture_height, image, shape, archived_at, owner_id
+		FROM furniture
+		WHERE lower(name) = ANY($1) AND archived_at IS NULL
+		AND (owner_id IS NULL OR owner_id = $2)
+		ORDER BY owner_id IS NOT NULL, furniture_id`
+
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := f.DB.QueryContext(ctx, query)
+	rows, err := f.DB.QueryContext(ctx, query, pq.Array(lower), userID)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	furnitureIDs := make([]int64, 0)
+	furnitures := make(map[string][]*Furniture)
+
+	for rows.Next() {
+
+		var furniture Furniture
+
+		err := rows.Scan(
+			&furniture.ID,
+			&furniture.Name,
+			&furniture.Price,
+			&furniture.Description,
+			&furniture.Width,
+			&furniture.Height,
+			&furniture.Image,
+			&furniture.Shape,
+			&furniture.ArchivedAt,
+			&furniture.OwnerID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		key := strings.ToLower(furniture.Name)
+		furnitures[key] = append(furnitures[key], &furniture)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return furnitures, nil
+}
+
+// GetMissingIDs returns those of the given IDs which don't belong to any item
+// visible to the user, in the order they were passed in.
+func (f FurnitureModel) GetMissingIDs(ids []int64, userID int64) ([]int64, error) {
+	query := `
+		SELECT furniture_id
+		FROM furniture
+		WHERE furniture_id = ANY($1)
+		AND (owner_id IS NULL OR owner_id = $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := f.DB.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	found := make(map[int64]bool)
 
 	for rows.Next() {
 
@@ -186,14 +338,22 @@ func (f FurnitureModel) GetAllID() ([]int64, error) {
 			return nil, err
 		}
 
-		furnitureIDs = append(furnitureIDs, furnitureID)
+		found[furnitureID] = true
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return furnitureIDs, nil
+	missing := []int64{}
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+			found[id] = true // Report every unknown ID only once
+		}
+	}
+
+	return missing, nil
 }
 
 func (f FurnitureModel) Update(furniture *Furniture) error {
@@ -223,19 +383,42 @@ func (f FurnitureModel) Update(furniture *Furniture) error {
 	return err
 }
 
-func (f FurnitureModel) Delete(id int64) error {
-	if id < 1 {
-		return ErrRecordNotFound
+// Archive marks the catalog item as discontinued. Archived items are hidden
+// from the catalog but stay in the rooms they were already placed in.
+func (f FurnitureModel) Archive(furniture *Furniture) error {
+	query := `
+		UPDATE furniture
+		SET archived_at = COALESCE(archived_at, NOW())
+		WHERE furniture_id = $1
+		RETURNING archived_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := f.DB.QueryRowContext(ctx, query, furniture.ID).Scan(&furniture.ArchivedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
 	}
 
+	return nil
+}
+
+// Restore puts an archived catalog item back into the catalog.
+func (f FurnitureModel) Restore(furniture *Furniture) error {
 	query := `
-		DELETE FROM furniture
+		UPDATE furniture
+		SET archived_at = NULL
 		WHERE furniture_id = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := f.DB.ExecContext(ctx, query, id)
+	result, err := f.DB.ExecContext(ctx, query, furniture.ID)
 	if err != nil {
 		return err
 	}
@@ -249,5 +432,7 @@ func (f FurnitureModel) Delete(id int64) error {
 		return ErrRecordNotFound
 	}
 
+	furniture.ArchivedAt = nil
+
 	return nil
 }
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	FurnitureCreated  = "create"
+	FurnitureUpdated  = "update"
+	FurnitureArchived = "archive"
+	FurnitureRestored = "restore"
+)
+
+// FurnitureChange records who changed a catalog item, how, and what the item
+// looked like afterwards.
+type FurnitureChange struct {
+	ID          int64      `json:"id"`
+	FurnitureID int64      `json:"furniture_id"`
+	UserID      *int64     `json:"user_id"` // nil once the user has been deleted
+	Action      string     `json:"action"`
+	Furniture   *Furniture `json:"furniture"`
+	ChangedAt   time.Time  `json:"changed_at"`
+}
+
+type FurnitureChangeModel struct {
+	DB DBTX
+}
+
+func (m FurnitureChangeModel) Insert(userID int64, action string, furniture *Furniture) error {
+	query := `
+		INSERT INTO furniture_changes (furniture_id, user_id, action, furniture)
+		VALUES ($1, $2, $3, $4)`
+
+	snapshot, err := json.Marshal(furniture)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{furniture.ID, userID, action, snapshot}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m FurnitureChangeModel) GetAllForFurniture(furnitureID int64) ([]*FurnitureChange, error) {
+	query := `
+		SELECT id, furniture_id, user_id, action, furniture, changed_at
+		FROM furniture_changes
+		WHERE furniture_id = $1
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, furnitureID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	changes := []*FurnitureChange{}
+
+	for rows.Next() {
+
+		var change FurnitureChange
+		var snapshot []byte
+
+		err := rows.Scan(
+			&change.ID,
+			&change.FurnitureID,
+			&change.UserID,
+			&change.Action,
+			&snapshot,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(snapshot, &change.Furniture)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// GetLatestForRooms returns the ID of the latest change to any item placed in
+// each of the rooms, keyed by room ID. Rooms whose items never changed are
+// left out.
+func (m FurnitureChangeModel) GetLatestForRooms(ids []int64) (map[int64]int64, error) {
+	query := `
+		SELECT room_furniture.room_id, MAX(furniture_changes.id)
+		FROM room_furniture
+		INNER JOIN furniture_changes ON furniture_changes.furniture_id = room_furniture.furniture_id
+		WHERE room_furniture.room_id = ANY($1)
+		GROUP BY room_furniture.room_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	latest := make(map[int64]int64)
+
+	for rows.Next() {
+
+		var roomID, changeID int64
+
+		err := rows.Scan(&roomID, &changeID)
+		if err != nil {
+			return nil, err
+		}
+
+		latest[roomID] = changeID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return latest, nil
+}
@@ -3,59 +3,300 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
+	"github.com/WrastAct/EHome/internal/geometry"
 	"github.com/WrastAct/EHome/internal/validator"
+
+	"github.com/lib/pq"
 )
 
 type FurnitureList struct {
-	FurnitureID int64 `json:"furniture_id"`
-	RoomID      int64 `json:"room_id"`
-	X           int64 `json:"x"`
-	Y           int64 `json:"y"`
+	ID           int64   `json:"id"` // Unique ID of the placement
+	FurnitureID  int64   `json:"furniture_id"`
+	RoomID       int64   `json:"room_id"`
+	X            int64   `json:"x"`
+	Y            int64   `json:"y"`
+	Rotation     float64 `json:"rotation"`               // Clockwise, in degrees
+	Width        int64   `json:"width,omitempty"`        // Width of the rotated footprint
+	Height       int64   `json:"height,omitempty"`       // Height of the rotated footprint
+	Discontinued bool    `json:"discontinued,omitempty"` // The catalog item has been archived
 }
 
 func ValidateFurnitureList(v *validator.Validator, flist *FurnitureList) {
 	v.Check(flist.X >= 0, "x", "must be positive")
 	v.Check(flist.Y >= 0, "y", "must be positive")
+	v.Check(flist.Rotation >= 0, "rotation", "must be positive")
+	v.Check(flist.Rotation < 360, "rotation", "must be less than 360")
+}
+
+// Footprint returns the area the placement occupies in the room, based on the
+// shape and dimensions of the catalog item it refers to. X and Y are the
+// top-left corner of the bounding box of the rotated item, which is turned
+// around its centre. Circles are inscribed into the Width x Height box of the
+// item.
+func (fl FurnitureList) Footprint(furniture *Furniture) geometry.Shape {
+	width, height := float64(furniture.Width), float64(furniture.Height)
+	boundsWidth, boundsHeight := geometry.RotatedSize(width, height, fl.Rotation)
+
+	cx := float64(fl.X) + boundsWidth/2
+	cy := float64(fl.Y) + boundsHeight/2
+
+	if furniture.Shape == Circle {
+		return geometry.Circle{X: cx, Y: cy, Radius: math.Min(width, height) / 2}
+	}
+
+	return geometry.RotatedRect(cx, cy, width, height, fl.Rotation)
+}
+
+// SetFootprint fills in the Width and Height of the rotated footprint of the
+// placement from the dimensions of the catalog item.
+func (fl *FurnitureList) SetFootprint(furniture *Furniture) {
+	width, height := geometry.RotatedSize(float64(furniture.Width), float64(furniture.Height), fl.Rotation)
+
+	// Round away the floating point noise before rounding up to whole units.
+	fl.Width = int64(math.Ceil(math.Round(width*1e6) / 1e6))
+	fl.Height = int64(math.Ceil(math.Round(height*1e6) / 1e6))
+}
+
+// ValidateFurnitureIDs adds an error for every placement in flist which refers
+// to one of the missing catalog IDs.
+func ValidateFurnitureIDs(v *validator.Validator, flist []FurnitureList, missing []int64) {
+	for i, val := range flist {
+		v.Check(!validator.InInts(val.FurnitureID, missing...), fmt.Sprintf("furniture_list[%d].furniture_id", i),
+			fmt.Sprintf("no furniture with id %d", val.FurnitureID))
+	}
+}
+
+// ValidatePlacementIDs adds an error for every placement in flist whose ID is
+// not one of the previous placements of the room, or is used more than once.
+// Placements without an ID are new.
+func ValidatePlacementIDs(v *validator.Validator, flist []FurnitureList, previous []FurnitureList) {
+	placed := make(map[int64]bool, len(previous))
+	for _, val := range previous {
+		placed[val.ID] = true
+	}
+
+	seen := make(map[int64]bool, len(flist))
+
+	for i, val := range flist {
+		if val.ID == 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("furniture_list[%d].id", i)
+
+		v.Check(placed[val.ID], key, fmt.Sprintf("no placement with id %d in this room", val.ID))
+		v.Check(!seen[val.ID], key, "must be unique")
+		seen[val.ID] = true
+	}
+}
+
+// ValidateArchivedFurniture adds an error for every placement in flist which
+// refers to an archived catalog item. As many placements of an item as the
+// previous layout of the room had may stay, so that discontinued furniture
+// doesn't block editing a room.
+func ValidateArchivedFurniture(v *validator.Validator, flist []FurnitureList, previous []FurnitureList, furniture map[int64]*Furniture) {
+	placed := make(map[int64]int)
+	for _, val := range previous {
+		placed[val.FurnitureID]++
+	}
+
+	for i, val := range flist {
+		f, ok := furniture[val.FurnitureID]
+		if !ok || f.ArchivedAt == nil {
+			continue
+		}
+
+		if placed[val.FurnitureID] > 0 {
+			placed[val.FurnitureID]--
+			continue
+		}
+
+		v.AddError(fmt.Sprintf("furniture_list[%d].furniture_id", i), fmt.Sprintf("%s has been discontinued", f.Name))
+	}
+}
+
+// ValidateFurnitureBounds adds an error for every placement of the room which
+// doesn't fit inside the room. The furniture map must contain the catalog items
+// for all referenced furniture IDs.
+func ValidateFurnitureBounds(v *validator.Validator, room *Room, furniture map[int64]*Furniture) {
+	for i, val := range room.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		checkBounds(v, room, f, val.Footprint(f), fmt.Sprintf("furniture_list[%d].x", i), fmt.Sprintf("furniture_list[%d].y", i))
+	}
+}
+
+func checkBounds(v *validator.Validator, room *Room, f *Furniture, footprint geometry.Shape, keyX, keyY string) {
+	bounds := footprint.Bounds()
+
+	v.Check(bounds.X+bounds.Width <= float64(room.Width), keyX,
+		fmt.Sprintf("%s must fit within the room width of %d", f.Name, room.Width))
+	v.Check(bounds.Y+bounds.Height <= float64(room.Height), keyY,
+		fmt.Sprintf("%s must fit within the room height of %d", f.Name, room.Height))
+}
+
+// ValidateFurnitureCollisions adds an error for every placement in flist which
+// overlaps another one. The furniture map must contain the catalog items for
+// all referenced furniture IDs.
+func ValidateFurnitureCollisions(v *validator.Validator, flist []FurnitureList, furniture map[int64]*Furniture) {
+	footprints := make([]geometry.Shape, len(flist))
+	for i, val := range flist {
+		if f, ok := furniture[val.FurnitureID]; ok {
+			footprints[i] = val.Footprint(f)
+		}
+	}
+
+	collisions := make(map[int][]string)
+
+	for i := range footprints {
+		for j := i + 1; j < len(footprints); j++ {
+			if footprints[i] == nil || footprints[j] == nil {
+				continue
+			}
+			if geometry.Intersects(footprints[i], footprints[j]) {
+				collisions[i] = append(collisions[i], placementName(j, flist[j], furniture))
+				collisions[j] = append(collisions[j], placementName(i, flist[i], furniture))
+			}
+		}
+	}
+
+	for i := range flist {
+		if names, ok := collisions[i]; ok {
+			v.AddError(fmt.Sprintf("furniture_list[%d]", i), "overlaps with "+strings.Join(names, ", "))
+		}
+	}
+}
+
+// ValidatePlacement checks a single placement against the boundaries of the
+// room and the number of placements it may hold and, unless overlapping
+// furniture is allowed, against the other placements of the room. A placement
+// of the room with the same ID as p is the one being moved, so it is skipped.
+func ValidatePlacement(v *validator.Validator, room *Room, p *FurnitureList, furniture map[int64]*Furniture, allowOverlap bool) {
+	f, ok := furniture[p.FurnitureID]
+	if !ok {
+		v.AddError("furniture_id", "no furniture with this id")
+		return
+	}
+
+	if f.ArchivedAt != nil {
+		// Moving a discontinued item which is already in the room is fine.
+		placed := false
+		for _, val := range room.FurnitureList {
+			if p.ID != 0 && val.ID == p.ID && val.FurnitureID == p.FurnitureID {
+				placed = true
+			}
+		}
+		v.Check(placed, "furniture_id", fmt.Sprintf("%s has been discontinued", f.Name))
+	}
+
+	v.Check(p.ID != 0 || len(room.FurnitureList) < MaxPlacements, "room",
+		fmt.Sprintf("must not contain more than %d placements", MaxPlacements))
+
+	footprint := p.Footprint(f)
+	checkBounds(v, room, f, footprint, "x", "y")
+
+	if allowOverlap {
+		return
+	}
+
+	var names []string
+
+	for _, val := range room.FurnitureList {
+		if p.ID != 0 && val.ID == p.ID {
+			continue
+		}
+
+		other, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		if geometry.Intersects(footprint, val.Footprint(other)) {
+			names = append(names, fmt.Sprintf("placement %d (%s)", val.ID, other.Name))
+		}
+	}
+
+	if len(names) > 0 {
+		v.AddError("placement", "overlaps with "+strings.Join(names, ", "))
+	}
+}
+
+func placementName(i int, fl FurnitureList, furniture map[int64]*Furniture) string {
+	return fmt.Sprintf("furniture_list[%d] (%s)", i, furniture[fl.FurnitureID].Name)
 }
 
 type FurnitureListModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func (fl FurnitureListModel) GetAll(id int64) ([]FurnitureList, error) {
+	furnitureLists, err := fl.GetAllForRooms([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+
+	if furnitureLists[id] == nil {
+		return []FurnitureList{}, nil
+	}
+
+	return furnitureLists[id], nil
+}
+
+// GetAllForRooms loads the placements of several rooms with a single query and
+// returns them keyed by room ID. Rooms without furniture are left out.
+func (fl FurnitureListModel) GetAllForRooms(ids []int64) (map[int64][]FurnitureList, error) {
 	query := `
-		SELECT furniture_id, x, y
-		FROM room_furniture 
-		WHERE room_id = $1`
+		SELECT placement_id, room_furniture.furniture_id, room_id, x, y, rotation,
+			furniture.furniture_width, furniture.furniture_height, furniture.archived_at IS NOT NULL
+		FROM room_furniture
+		INNER JOIN furniture ON furniture.furniture_id = room_furniture.furniture_id
+		WHERE room_id = ANY($1)
+		ORDER BY room_id, placement_id`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := fl.DB.QueryContext(ctx, query, id)
+	rows, err := fl.DB.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	furnitureLists := []FurnitureList{}
+	furnitureLists := make(map[int64][]FurnitureList)
 
 	for rows.Next() {
 
 		var furnitureList FurnitureList
+		var furniture Furniture
 
 		err := rows.Scan(
+			&furnitureList.ID,
 			&furnitureList.FurnitureID,
+			&furnitureList.RoomID,
 			&furnitureList.X,
 			&furnitureList.Y,
+			&furnitureList.Rotation,
+			&furniture.Width,
+			&furniture.Height,
+			&furnitureList.Discontinued,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		furnitureLists = append(furnitureLists, furnitureList)
+		furnitureList.SetFootprint(&furniture)
+
+		furnitureLists[furnitureList.RoomID] = append(furnitureLists[furnitureList.RoomID], furnitureList)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -65,53 +306,139 @@ func (fl FurnitureListModel) GetAll(id int64) ([]FurnitureList, error) {
 	return furnitureLists, nil
 }
 
+func (fl FurnitureListModel) Get(roomID, placementID int64) (*FurnitureList, error) {
+	if roomID < 1 || placementID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT placement_id, room_furniture.furniture_id, room_id, x, y, rotation,
+			furniture.furniture_width, furniture.furniture_height, furniture.archived_at IS NOT NULL
+		FROM room_furniture
+		INNER JOIN furniture ON furniture.furniture_id = room_furniture.furniture_id
+		WHERE room_id = $1 AND placement_id = $2`
+
+	var furnitureList FurnitureList
+	var furniture Furniture
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := fl.DB.QueryRowContext(ctx, query, roomID, placementID).Scan(
+		&furnitureList.ID,
+		&furnitureList.FurnitureID,
+		&furnitureList.RoomID,
+		&furnitureList.X,
+		&furnitureList.Y,
+		&furnitureList.Rotation,
+		&furniture.Width,
+		&furniture.Height,
+		&furnitureList.Discontinued,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	furnitureList.SetFootprint(&furniture)
+
+	return &furnitureList, nil
+}
+
 func (fl FurnitureListModel) Insert(flist *FurnitureList) error {
 	query := `
-		INSERT INTO room_furniture (furniture_id, room_id, x, y)
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO room_furniture (furniture_id, room_id, x, y, rotation)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING placement_id`
 
 	args := []interface{}{
 		flist.FurnitureID,
 		flist.RoomID,
 		flist.X,
 		flist.Y,
+		flist.Rotation,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := fl.DB.ExecContext(ctx, query, args...)
-
-	return err
+	return fl.DB.QueryRowContext(ctx, query, args...).Scan(&flist.ID)
 }
 
-func (fl FurnitureListModel) InsertTransaction(flist []FurnitureList) error {
+// InsertAll inserts every placement of flist and fills in their IDs. Run it
+// inside Models.Transaction so that a failure doesn't leave a partial list.
+func (fl FurnitureListModel) InsertAll(flist []FurnitureList) error {
 	query := `
-		INSERT INTO room_furniture (furniture_id, room_id, x, y)
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO room_furniture (furniture_id, room_id, x, y, rotation)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING placement_id`
 
-	tx, err := fl.DB.Begin()
-	if err != nil {
-		return err
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	for _, val := range flist {
+	for i, val := range flist {
 		args := []interface{}{
 			val.FurnitureID,
 			val.RoomID,
 			val.X,
 			val.Y,
+			val.Rotation,
 		}
 
-		_, err = tx.Exec(query, args...)
+		err := fl.DB.QueryRowContext(ctx, query, args...).Scan(&flist[i].ID)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
 
-	err = tx.Commit()
-	return err
+	return nil
+}
+
+// ReplaceAll makes flist the layout of the room. Placements with an ID are
+// updated in place, so they keep their IDs, placements without one are
+// inserted and the placements of the room missing from flist are deleted. Run
+// it inside Models.Transaction so that a failure doesn't leave a partial list.
+func (fl FurnitureListModel) ReplaceAll(roomID int64, flist []FurnitureList) error {
+	kept := make([]int64, 0, len(flist))
+	for _, val := range flist {
+		if val.ID != 0 {
+			kept = append(kept, val.ID)
+		}
+	}
+
+	query := `
+		DELETE FROM room_furniture
+		WHERE room_id = $1 AND NOT placement_id = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := fl.DB.ExecContext(ctx, query, roomID, pq.Array(kept))
+	if err != nil {
+		return err
+	}
+
+	for i := range flist {
+		if flist[i].ID != 0 {
+			err = fl.Update(&flist[i])
+		} else {
+			err = fl.Insert(&flist[i])
+		}
+		if err != nil {
+			// The placement was deleted since the layout was checked.
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrEditConflict
+			}
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (fl FurnitureListModel) Delete(id int64) error {
@@ -133,3 +460,67 @@ func (fl FurnitureListModel) Delete(id int64) error {
 
 	return nil
 }
+
+func (fl FurnitureListModel) Update(flist *FurnitureList) error {
+	query := `
+		UPDATE room_furniture
+		SET furniture_id = $1, x = $2, y = $3, rotation = $4
+		WHERE room_id = $5 AND placement_id = $6`
+
+	args := []interface{}{
+		flist.FurnitureID,
+		flist.X,
+		flist.Y,
+		flist.Rotation,
+		flist.RoomID,
+		flist.ID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := fl.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (fl FurnitureListModel) DeletePlacement(roomID, placementID int64) error {
+	if roomID < 1 || placementID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM room_furniture
+		WHERE room_id = $1 AND placement_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := fl.DB.ExecContext(ctx, query, roomID, placementID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
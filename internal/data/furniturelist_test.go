@@ -0,0 +1,189 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func TestValidateArchivedFurniture(t *testing.T) {
+	archivedAt := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	furniture := map[int64]*Furniture{
+		1: {ID: 1, Name: "Chair"},
+		2: {ID: 2, Name: "Old sofa", ArchivedAt: &archivedAt},
+		3: {ID: 3, Name: "Old lamp", ArchivedAt: &archivedAt},
+	}
+
+	placements := func(ids ...int64) []FurnitureList {
+		flist := make([]FurnitureList, len(ids))
+		for i, id := range ids {
+			flist[i] = FurnitureList{FurnitureID: id, X: int64(i) * 100}
+		}
+		return flist
+	}
+
+	tests := []struct {
+		name     string
+		flist    []FurnitureList
+		previous []FurnitureList
+		want     map[string]string
+	}{
+		{"available items", placements(1, 1), nil, map[string]string{}},
+		{"new archived item", placements(1, 2), nil, map[string]string{
+			"furniture_list[1].furniture_id": "Old sofa has been discontinued",
+		}},
+		{"archived item kept", placements(2, 1), placements(2), map[string]string{}},
+		{"archived item moved", placements(1, 3, 3), placements(3, 3, 1), map[string]string{}},
+		{"archived item copied", placements(2, 2, 2), placements(2), map[string]string{
+			"furniture_list[1].furniture_id": "Old sofa has been discontinued",
+			"furniture_list[2].furniture_id": "Old sofa has been discontinued",
+		}},
+		{"other archived item", placements(3), placements(2), map[string]string{
+			"furniture_list[0].furniture_id": "Old lamp has been discontinued",
+		}},
+		{"unknown item", placements(9), nil, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		ValidateArchivedFurniture(v, tt.flist, tt.previous, furniture)
+		if !reflect.DeepEqual(v.Errors, tt.want) {
+			t.Errorf("%s: errors = %v, want %v", tt.name, v.Errors, tt.want)
+		}
+	}
+}
+
+// The benchmarks need a migrated database, given by EHOME_TEST_DB_DSN. Their
+// rows are created in a transaction which is rolled back afterwards.
+const benchmarkRooms = 50
+
+// countingDB counts the queries sent to the database, so the benchmarks can
+// report the round trips per operation next to the time.
+type countingDB struct {
+	DBTX
+	queries int
+}
+
+func (db *countingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db.queries++
+	return db.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (db *countingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db.queries++
+	return db.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+// setupRooms creates benchmarkRooms rooms with ten placements each and
+// returns their IDs together with a counting handle on the transaction.
+func setupRooms(b *testing.B) ([]int64, *countingDB) {
+	dsn := os.Getenv("EHOME_TEST_DB_DSN")
+	if dsn == "" {
+		b.Skip("EHOME_TEST_DB_DSN not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { tx.Rollback() })
+
+	models := newModels(tx)
+
+	user := &User{Name: "Benchmark", Email: "benchmark@example.com"}
+	err = user.Password.Set("pa55word")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	err = models.Users.Insert(user)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	furniture := &Furniture{Name: "Chair", Width: 40, Height: 40, Shape: Rectangle}
+	err = models.Furniture.Insert(furniture)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ids := make([]int64, 0, benchmarkRooms)
+
+	for i := 0; i < benchmarkRooms; i++ {
+		room := &Room{
+			OwnerID:    user.ID,
+			Title:      fmt.Sprintf("Room %d", i),
+			Width:      500,
+			Height:     500,
+			Visibility: VisibilityPrivate,
+		}
+
+		err = models.Room.Insert(room)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		flist := make([]FurnitureList, 10)
+		for j := range flist {
+			flist[j] = FurnitureList{FurnitureID: furniture.ID, RoomID: room.ID, X: int64(j) * 50}
+		}
+
+		err = models.FurnitureList.InsertAll(flist)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		ids = append(ids, room.ID)
+	}
+
+	return ids, &countingDB{DBTX: tx}
+}
+
+func BenchmarkGetAllForRooms(b *testing.B) {
+	ids, db := setupRooms(b)
+	model := FurnitureListModel{DB: db}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := model.GetAllForRooms(ids)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(db.queries)/float64(b.N), "queries/op")
+}
+
+// BenchmarkGetAllPerRoom loads the same placements one room at a time, as
+// the room listing did before GetAllForRooms.
+func BenchmarkGetAllPerRoom(b *testing.B) {
+	ids, db := setupRooms(b)
+	model := FurnitureListModel{DB: db}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, id := range ids {
+			_, err := model.GetAll(id)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.ReportMetric(float64(db.queries)/float64(b.N), "queries/op")
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -10,22 +11,72 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so the models can run their
+// queries either directly against the pool or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type Models struct {
-	Furniture     FurnitureModel
-	FurnitureList FurnitureListModel
-	Permissions   PermissionModel
-	Room          RoomModel
-	Tokens        TokenModel
-	Users         UserModel
+	db               *sql.DB // nil when the models are bound to a transaction
+	Collaborators    CollaboratorModel
+	Furniture        FurnitureModel
+	FurnitureChanges FurnitureChangeModel
+	FurnitureList    FurnitureListModel
+	Permissions      PermissionModel
+	Revisions        RevisionModel
+	Room             RoomModel
+	ShareLinks       ShareLinkModel
+	Templates        TemplateModel
+	Tokens           TokenModel
+	Users            UserModel
 }
 
 func NewModels(db *sql.DB) Models {
+	models := newModels(db)
+	models.db = db
+	return models
+}
+
+func newModels(db DBTX) Models {
 	return Models{
-		Furniture:     FurnitureModel{DB: db},
-		FurnitureList: FurnitureListModel{DB: db},
-		Permissions:   PermissionModel{DB: db},
-		Room:          RoomModel{DB: db},
-		Tokens:        TokenModel{DB: db},
-		Users:         UserModel{DB: db},
+		Collaborators:    CollaboratorModel{DB: db},
+		Furniture:        FurnitureModel{DB: db},
+		FurnitureChanges: FurnitureChangeModel{DB: db},
+		FurnitureList:    FurnitureListModel{DB: db},
+		Permissions:      PermissionModel{DB: db},
+		Revisions:        RevisionModel{DB: db},
+		Room:             RoomModel{DB: db},
+		ShareLinks:       ShareLinkModel{DB: db},
+		Templates:        TemplateModel{DB: db},
+		Tokens:           TokenModel{DB: db},
+		Users:            UserModel{DB: db},
+	}
+}
+
+// Transaction runs fn with a copy of the models whose queries all belong to a
+// single database transaction. The transaction is committed when fn returns
+// nil and rolled back otherwise. Calling Transaction on the models passed to
+// fn runs the nested function as part of the same transaction.
+func (m Models) Transaction(fn func(tx Models) error) error {
+	if m.db == nil {
+		return fn(m)
 	}
+
+	tx, err := m.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed.
+	defer tx.Rollback()
+
+	err = fn(newModels(tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
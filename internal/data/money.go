@@ -0,0 +1,149 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMoneyFormat = errors.New("invalid money format")
+	ErrMoneyOverflow      = errors.New("amount of money is too large")
+)
+
+// Money is an exact amount in cents, matching the NUMERIC(20, 2) columns of
+// the database. It is encoded in JSON as a plain number with two decimals so
+// that prices never pass through float64. Amounts beyond the int64 range,
+// which the columns could still hold, are reported as ErrMoneyOverflow.
+type Money int64
+
+// ParseMoney parses a decimal amount such as "12", "12.5" or "-0.99". More
+// than two fractional digits are rejected rather than rounded.
+func ParseMoney(s string) (Money, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || len(fraction) > 2 {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	for _, part := range []string{whole, fraction} {
+		if strings.Trim(part, "0123456789") != "" {
+			return 0, ErrInvalidMoneyFormat
+		}
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, ErrMoneyOverflow
+		}
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	fraction += strings.Repeat("0", 2-len(fraction))
+	cents, _ := strconv.ParseInt(fraction, 10, 64)
+
+	if units > (math.MaxInt64-cents)/100 {
+		return 0, ErrMoneyOverflow
+	}
+
+	m := Money(units*100 + cents)
+	if negative {
+		m = -m
+	}
+
+	return m, nil
+}
+
+func (m Money) String() string {
+	sign := ""
+	cents := uint64(m)
+	if m < 0 {
+		sign = "-"
+		cents = -cents // Also right for the smallest int64, whose negation doesn't fit
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Add returns the sum of both amounts, or ErrMoneyOverflow.
+func (m Money) Add(other Money) (Money, error) {
+	sum := m + other
+	if (other > 0 && sum < m) || (other < 0 && sum > m) {
+		return 0, ErrMoneyOverflow
+	}
+	return sum, nil
+}
+
+// Mul returns the amount multiplied by a quantity, or ErrMoneyOverflow.
+func (m Money) Mul(quantity int64) (Money, error) {
+	if m == 0 || quantity == 0 {
+		return 0, nil
+	}
+
+	product := m * Money(quantity)
+	if product/Money(quantity) != m || (quantity == -1 && m == math.MinInt64) {
+		return 0, ErrMoneyOverflow
+	}
+
+	return product, nil
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts the amount either as a JSON number or as a string.
+func (m *Money) UnmarshalJSON(jsonValue []byte) error {
+	s := string(jsonValue)
+
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner. PostgreSQL returns NUMERIC values as text.
+func (m *Money) Scan(src interface{}) error {
+	var s string
+
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		cents, err := Money(v).Mul(100)
+		if err != nil {
+			return err
+		}
+		*m = cents
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, sending the amount as a decimal string.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,190 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		err   error
+	}{
+		{"0", 0, nil},
+		{"12", 1200, nil},
+		{"12.5", 1250, nil},
+		{"12.05", 1205, nil},
+		{"0.99", 99, nil},
+		{"-0.99", -99, nil},
+		{"-12", -1200, nil},
+		{"007.10", 710, nil},
+		{"12.", 1200, nil},
+		{"92233720368547758.07", math.MaxInt64, nil},
+		{"-92233720368547758.07", -math.MaxInt64, nil},
+
+		{"92233720368547758.08", 0, ErrMoneyOverflow},
+		{"999999999999999999.99", 0, ErrMoneyOverflow},
+		{"99999999999999999999", 0, ErrMoneyOverflow},
+
+		{"", 0, ErrInvalidMoneyFormat},
+		{"-", 0, ErrInvalidMoneyFormat},
+		{".5", 0, ErrInvalidMoneyFormat},
+		{"1.234", 0, ErrInvalidMoneyFormat},
+		{"1e3", 0, ErrInvalidMoneyFormat},
+		{"+1", 0, ErrInvalidMoneyFormat},
+		{"--1", 0, ErrInvalidMoneyFormat},
+		{"1.-5", 0, ErrInvalidMoneyFormat},
+		{"1,50", 0, ErrInvalidMoneyFormat},
+		{" 1", 0, ErrInvalidMoneyFormat},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.input)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseMoney(%q) error = %v, want %v", tt.input, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		input Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{99, "0.99"},
+		{1250, "12.50"},
+		{-1, "-0.01"},
+		{-1250, "-12.50"},
+		{math.MaxInt64, "92233720368547758.07"},
+		{math.MinInt64, "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.input.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.input), got, tt.want)
+		}
+
+		if tt.input == math.MinInt64 {
+			continue // Only reachable through arithmetic, not by parsing
+		}
+
+		parsed, err := ParseMoney(tt.input.String())
+		if err != nil || parsed != tt.input {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", tt.input.String(), parsed, err, int64(tt.input))
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	add := []struct {
+		a, b Money
+		want Money
+		err  error
+	}{
+		{100, 250, 350, nil},
+		{-100, 50, -50, nil},
+		{math.MaxInt64, 0, math.MaxInt64, nil},
+		{math.MaxInt64, 1, 0, ErrMoneyOverflow},
+		{math.MinInt64, -1, 0, ErrMoneyOverflow},
+		{math.MaxInt64, math.MinInt64, -1, nil},
+	}
+
+	for _, tt := range add {
+		got, err := tt.a.Add(tt.b)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Money(%d).Add(%d) = %d, %v, want %d, %v", int64(tt.a), int64(tt.b), got, err, tt.want, tt.err)
+		}
+	}
+
+	mul := []struct {
+		m        Money
+		quantity int64
+		want     Money
+		err      error
+	}{
+		{1250, 3, 3750, nil},
+		{1250, 0, 0, nil},
+		{0, math.MaxInt64, 0, nil},
+		{-1250, 2, -2500, nil},
+		{1250, -2, -2500, nil},
+		{math.MaxInt64, 1, math.MaxInt64, nil},
+		{math.MaxInt64, 2, 0, ErrMoneyOverflow},
+		{math.MaxInt64 / 3, 4, 0, ErrMoneyOverflow},
+		{math.MinInt64, -1, 0, ErrMoneyOverflow},
+		{-1, math.MinInt64, 0, ErrMoneyOverflow},
+		{3037000500, 3037000500, 0, ErrMoneyOverflow},
+	}
+
+	for _, tt := range mul {
+		got, err := tt.m.Mul(tt.quantity)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Money(%d).Mul(%d) = %d, %v, want %d, %v", int64(tt.m), tt.quantity, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	js, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{Price: -1205})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != `{"price":-12.05}` {
+		t.Errorf("json.Marshal = %s, want {\"price\":-12.05}", js)
+	}
+
+	tests := []struct {
+		input string
+		want  Money
+		err   bool
+	}{
+		{`12.05`, 1205, false},
+		{`"12.05"`, 1205, false},
+		{`12`, 1200, false},
+		{`1.005`, 0, true},
+		{`1e2`, 0, true},
+		{`"abc"`, 0, true},
+		{`92233720368547758.08`, 0, true},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.input), &m)
+		if (err != nil) != tt.err || m != tt.want {
+			t.Errorf("json.Unmarshal(%s) = %d, %v, want %d, error %t", tt.input, m, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Money
+		err  bool
+	}{
+		{[]byte("12.50"), 1250, false},
+		{"-3.00", -300, false},
+		{int64(7), 700, false},
+		{int64(math.MaxInt64 / 10), 0, true},
+		{"999999999999999999.99", 0, true},
+		{1.5, 0, true},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		err := m.Scan(tt.src)
+		if (err != nil) != tt.err || m != tt.want {
+			t.Errorf("Scan(%v) = %d, %v, want %d, error %t", tt.src, m, err, tt.want, tt.err)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package data
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/lib/pq"
@@ -19,8 +18,18 @@ func (p Permissions) Include(code string) bool {
 	return false
 }
 
+// IncludeAny reports whether at least one of the codes is included.
+func (p Permissions) IncludeAny(codes ...string) bool {
+	for _, code := range codes {
+		if p.Include(code) {
+			return true
+		}
+	}
+	return false
+}
+
 type PermissionModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
@@ -0,0 +1,152 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RoomRevision is an immutable snapshot of a room and its placements, taken
+// every time the room changes.
+type RoomRevision struct {
+	ID          int64     `json:"id"`
+	RoomID      int64     `json:"room_id"`
+	RoomVersion int       `json:"room_version"`
+	UserID      *int64    `json:"user_id"` // Author of the change, nil once the user has been deleted
+	CreatedAt   time.Time `json:"created_at"`
+	Room        *Room     `json:"room,omitempty"` // Only loaded for a single revision
+}
+
+type RevisionModel struct {
+	DB DBTX
+}
+
+func (m RevisionModel) Insert(room *Room, userID int64) error {
+	query := `
+		INSERT INTO room_revisions (room_id, room_version, user_id, snapshot)
+		VALUES ($1, $2, $3, $4)`
+
+	snapshot, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{room.ID, room.Version, userID, snapshot}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m RevisionModel) Get(roomID, id int64) (*RoomRevision, error) {
+	if roomID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, room_id, room_version, user_id, created_at, snapshot
+		FROM room_revisions
+		WHERE room_id = $1 AND id = $2`
+
+	var revision RoomRevision
+	var snapshot []byte
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, roomID, id).Scan(
+		&revision.ID,
+		&revision.RoomID,
+		&revision.RoomVersion,
+		&revision.UserID,
+		&revision.CreatedAt,
+		&snapshot,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = json.Unmarshal(snapshot, &revision.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// GetAllForRoom lists the revisions of the room, newest first, without their
+// snapshots.
+func (m RevisionModel) GetAllForRoom(roomID int64) ([]*RoomRevision, error) {
+	query := `
+		SELECT id, room_id, room_version, user_id, created_at
+		FROM room_revisions
+		WHERE room_id = $1
+		ORDER BY id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []*RoomRevision{}
+
+	for rows.Next() {
+
+		var revision RoomRevision
+
+		err := rows.Scan(
+			&revision.ID,
+			&revision.RoomID,
+			&revision.RoomVersion,
+			&revision.UserID,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Prune deletes the revisions of the room beyond the newest keep revisions and
+// those older than maxAge. A zero keep or maxAge disables that limit.
+func (m RevisionModel) Prune(roomID int64, keep int, maxAge time.Duration) error {
+	query := `
+		DELETE FROM room_revisions
+		WHERE room_id = $1
+		AND (
+			($2 > 0 AND id NOT IN (
+				SELECT id FROM room_revisions WHERE room_id = $1 ORDER BY id DESC LIMIT $2))
+			OR ($3 > 0 AND created_at < $4)
+		)`
+
+	args := []interface{}{roomID, keep, int64(maxAge), time.Now().Add(-maxAge)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,178 @@
+package data
+
+import (
+	"math"
+	"sort"
+)
+
+// FieldChange is a room attribute which differs between two rooms.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// PlacementDiff describes a placement which only exists in one of the rooms,
+// or which exists in both at a different position or rotation.
+type PlacementDiff struct {
+	FurnitureID   int64          `json:"furniture_id"`
+	Name          string         `json:"name,omitempty"`
+	From          *FurnitureList `json:"from,omitempty"`
+	To            *FurnitureList `json:"to,omitempty"`
+	DeltaX        int64          `json:"delta_x,omitempty"`
+	DeltaY        int64          `json:"delta_y,omitempty"`
+	DeltaRotation float64        `json:"delta_rotation,omitempty"`
+}
+
+type RoomDiff struct {
+	RoomID          int64                  `json:"room_id"`
+	OtherID         int64                  `json:"other_id"`
+	Changes         map[string]FieldChange `json:"changes"`
+	Added           []PlacementDiff        `json:"added"`
+	Removed         []PlacementDiff        `json:"removed"`
+	Moved           []PlacementDiff        `json:"moved"`
+	Unchanged       int                    `json:"unchanged"`
+	Price           Money                  `json:"price"`
+	OtherPrice      Money                  `json:"other_price"`
+	PriceDifference Money                  `json:"price_difference"` // OtherPrice - Price
+}
+
+// DiffRooms compares the layout of room with the layout of other. Placements
+// of the same furniture at the same position and rotation are unchanged. The
+// remaining placements of each furniture item are paired up nearest first and
+// reported as moved; whatever is left over was added or removed.
+func DiffRooms(room, other *Room, furniture map[int64]*Furniture) (*RoomDiff, error) {
+	diff := &RoomDiff{
+		RoomID:  room.ID,
+		OtherID: other.ID,
+		Changes: map[string]FieldChange{},
+		Added:   []PlacementDiff{},
+		Removed: []PlacementDiff{},
+		Moved:   []PlacementDiff{},
+	}
+
+	if room.Title != other.Title {
+		diff.Changes["title"] = FieldChange{room.Title, other.Title}
+	}
+	if room.Description != other.Description {
+		diff.Changes["description"] = FieldChange{room.Description, other.Description}
+	}
+	if room.Width != other.Width {
+		diff.Changes["width"] = FieldChange{room.Width, other.Width}
+	}
+	if room.Height != other.Height {
+		diff.Changes["height"] = FieldChange{room.Height, other.Height}
+	}
+	if !equalMoney(room.Budget, other.Budget) {
+		diff.Changes["budget"] = FieldChange{room.Budget, other.Budget}
+	}
+	if room.Visibility != other.Visibility {
+		diff.Changes["visibility"] = FieldChange{room.Visibility, other.Visibility}
+	}
+
+	name := func(furnitureID int64) string {
+		if f, ok := furniture[furnitureID]; ok {
+			return f.Name
+		}
+		return ""
+	}
+
+	from := make([]*FurnitureList, 0, len(room.FurnitureList))
+	for i := range room.FurnitureList {
+		from = append(from, &room.FurnitureList[i])
+	}
+
+	to := make([]*FurnitureList, 0, len(other.FurnitureList))
+	for i := range other.FurnitureList {
+		to = append(to, &other.FurnitureList[i])
+	}
+
+	// Exact matches first, so that a moved copy of an item doesn't steal the
+	// partner of one which stayed in place.
+	fromMatched := make([]bool, len(from))
+	toMatched := make([]bool, len(to))
+
+	for i, a := range from {
+		for j, b := range to {
+			if toMatched[j] || a.FurnitureID != b.FurnitureID {
+				continue
+			}
+			if a.X == b.X && a.Y == b.Y && a.Rotation == b.Rotation {
+				fromMatched[i], toMatched[j] = true, true
+				diff.Unchanged++
+				break
+			}
+		}
+	}
+
+	type pair struct {
+		i, j     int
+		distance float64
+	}
+
+	var pairs []pair
+	for i, a := range from {
+		if fromMatched[i] {
+			continue
+		}
+		for j, b := range to {
+			if toMatched[j] || a.FurnitureID != b.FurnitureID {
+				continue
+			}
+			pairs = append(pairs, pair{i, j, math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y))})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].distance < pairs[j].distance
+	})
+
+	for _, p := range pairs {
+		if fromMatched[p.i] || toMatched[p.j] {
+			continue
+		}
+		fromMatched[p.i], toMatched[p.j] = true, true
+
+		a, b := from[p.i], to[p.j]
+		diff.Moved = append(diff.Moved, PlacementDiff{
+			FurnitureID:   a.FurnitureID,
+			Name:          name(a.FurnitureID),
+			From:          a,
+			To:            b,
+			DeltaX:        b.X - a.X,
+			DeltaY:        b.Y - a.Y,
+			DeltaRotation: b.Rotation - a.Rotation,
+		})
+	}
+
+	sort.SliceStable(diff.Moved, func(i, j int) bool {
+		return diff.Moved[i].From.ID < diff.Moved[j].From.ID
+	})
+
+	for i, a := range from {
+		if !fromMatched[i] {
+			diff.Removed = append(diff.Removed, PlacementDiff{FurnitureID: a.FurnitureID, Name: name(a.FurnitureID), From: a})
+		}
+	}
+
+	for j, b := range to {
+		if !toMatched[j] {
+			diff.Added = append(diff.Added, PlacementDiff{FurnitureID: b.FurnitureID, Name: name(b.FurnitureID), To: b})
+		}
+	}
+
+	var err error
+
+	diff.Price, err = layoutPrice(room.FurnitureList, furniture)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.OtherPrice, err = layoutPrice(other.FurnitureList, furniture)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.PriceDifference = diff.OtherPrice - diff.Price
+
+	return diff, nil
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRooms(t *testing.T) {
+	furniture := map[int64]*Furniture{
+		1: {ID: 1, Name: "Chair", Price: 2500},
+		2: {ID: 2, Name: "Table", Price: 12000},
+		3: {ID: 3, Name: "Lamp", Price: 4000},
+	}
+
+	placement := func(id, furnitureID, x, y int64, rotation float64) FurnitureList {
+		return FurnitureList{ID: id, FurnitureID: furnitureID, X: x, Y: y, Rotation: rotation}
+	}
+
+	// Placements of the room have IDs below 10, those of the other room 10
+	// and above, so the result can be described by the pairs of IDs.
+	tests := []struct {
+		name      string
+		from, to  []FurnitureList
+		moved     []string
+		added     []int64
+		removed   []int64
+		unchanged int
+	}{
+		{
+			name:      "identical",
+			from:      []FurnitureList{placement(1, 1, 0, 0, 0), placement(2, 2, 100, 100, 90)},
+			to:        []FurnitureList{placement(11, 1, 0, 0, 0), placement(12, 2, 100, 100, 90)},
+			unchanged: 2,
+		},
+		{
+			name:      "moved",
+			from:      []FurnitureList{placement(1, 1, 0, 0, 0), placement(2, 2, 100, 100, 0)},
+			to:        []FurnitureList{placement(11, 1, 0, 0, 0), placement(12, 2, 150, 80, 0)},
+			moved:     []string{"2->12 (50, -20, 0)"},
+			unchanged: 1,
+		},
+		{
+			name:  "rotated in place",
+			from:  []FurnitureList{placement(1, 2, 100, 100, 0)},
+			to:    []FurnitureList{placement(11, 2, 100, 100, 90)},
+			moved: []string{"1->11 (0, 0, 90)"},
+		},
+		{
+			name:      "added and removed",
+			from:      []FurnitureList{placement(1, 1, 0, 0, 0), placement(2, 3, 50, 50, 0)},
+			to:        []FurnitureList{placement(11, 1, 0, 0, 0), placement(12, 2, 200, 200, 0)},
+			added:     []int64{12},
+			removed:   []int64{2},
+			unchanged: 1,
+		},
+		{
+			name:  "swapped",
+			from:  []FurnitureList{placement(1, 1, 0, 0, 0), placement(2, 3, 300, 0, 0)},
+			to:    []FurnitureList{placement(11, 3, 0, 0, 0), placement(12, 1, 300, 0, 0)},
+			moved: []string{"1->12 (300, 0, 0)", "2->11 (-300, 0, 0)"},
+		},
+		{
+			name:      "copy kept in place",
+			from:      []FurnitureList{placement(1, 1, 0, 0, 0), placement(2, 1, 100, 0, 0)},
+			to:        []FurnitureList{placement(11, 1, 100, 0, 0), placement(12, 1, 300, 0, 0)},
+			moved:     []string{"1->12 (300, 0, 0)"},
+			unchanged: 1,
+		},
+		{
+			name:  "copies paired nearest first",
+			from:  []FurnitureList{placement(1, 1, 0, 0, 0), placement(2, 1, 500, 0, 0)},
+			to:    []FurnitureList{placement(11, 1, 490, 0, 0), placement(12, 1, 10, 0, 0)},
+			moved: []string{"1->12 (10, 0, 0)", "2->11 (-10, 0, 0)"},
+		},
+		{
+			name:  "more copies than before",
+			from:  []FurnitureList{placement(1, 1, 0, 0, 0)},
+			to:    []FurnitureList{placement(11, 1, 400, 0, 0), placement(12, 1, 20, 0, 0)},
+			moved: []string{"1->12 (20, 0, 0)"},
+			added: []int64{11},
+		},
+		{
+			name: "empty rooms",
+		},
+	}
+
+	for _, tt := range tests {
+		room := &Room{ID: 1, Title: "Room", FurnitureList: tt.from}
+		other := &Room{ID: 2, Title: "Room", FurnitureList: tt.to}
+
+		diff, err := DiffRooms(room, other, furniture)
+		if err != nil {
+			t.Errorf("%s: DiffRooms error = %v", tt.name, err)
+			continue
+		}
+
+		moved := []string{}
+		for _, m := range diff.Moved {
+			moved = append(moved, fmt.Sprintf("%d->%d (%d, %d, %g)", m.From.ID, m.To.ID, m.DeltaX, m.DeltaY, m.DeltaRotation))
+		}
+
+		added := []int64{}
+		for _, a := range diff.Added {
+			added = append(added, a.To.ID)
+		}
+
+		removed := []int64{}
+		for _, r := range diff.Removed {
+			removed = append(removed, r.From.ID)
+		}
+
+		if tt.moved == nil {
+			tt.moved = []string{}
+		}
+		if tt.added == nil {
+			tt.added = []int64{}
+		}
+		if tt.removed == nil {
+			tt.removed = []int64{}
+		}
+
+		if !reflect.DeepEqual(moved, tt.moved) {
+			t.Errorf("%s: moved = %v, want %v", tt.name, moved, tt.moved)
+		}
+		if !reflect.DeepEqual(added, tt.added) {
+			t.Errorf("%s: added = %v, want %v", tt.name, added, tt.added)
+		}
+		if !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("%s: removed = %v, want %v", tt.name, removed, tt.removed)
+		}
+		if diff.Unchanged != tt.unchanged {
+			t.Errorf("%s: unchanged = %d, want %d", tt.name, diff.Unchanged, tt.unchanged)
+		}
+	}
+}
+
+func TestDiffRoomsFields(t *testing.T) {
+	furniture := map[int64]*Furniture{
+		1: {ID: 1, Name: "Chair", Price: 2500},
+		2: {ID: 2, Name: "Table", Price: 12000},
+	}
+
+	budget := Money(50000)
+
+	room := &Room{
+		ID:         1,
+		Title:      "Study",
+		Width:      300,
+		Height:     250,
+		Visibility: VisibilityPrivate,
+		FurnitureList: []FurnitureList{
+			{ID: 1, FurnitureID: 1},
+			{ID: 2, FurnitureID: 1, X: 100},
+		},
+	}
+
+	other := &Room{
+		ID:         2,
+		Title:      "Office",
+		Width:      300,
+		Height:     300,
+		Visibility: VisibilityPrivate,
+		Budget:     &budget,
+		FurnitureList: []FurnitureList{
+			{ID: 11, FurnitureID: 2},
+		},
+	}
+
+	diff, err := DiffRooms(room, other, furniture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]FieldChange{
+		"title":  {"Study", "Office"},
+		"height": {int64(250), int64(300)},
+		"budget": {(*Money)(nil), &budget},
+	}
+
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("Changes = %v, want %v", diff.Changes, want)
+	}
+
+	if diff.Price != 5000 || diff.OtherPrice != 12000 || diff.PriceDifference != 7000 {
+		t.Errorf("prices = %s, %s, %s, want 50.00, 120.00, 70.00", diff.Price, diff.OtherPrice, diff.PriceDifference)
+	}
+}
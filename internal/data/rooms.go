@@ -10,6 +10,19 @@ import (
 	"github.com/WrastAct/EHome/internal/validator"
 )
 
+const (
+	VisibilityPrivate  = "private"  // Only the owner can see the room
+	VisibilityUnlisted = "unlisted" // Anyone with the room ID can see it
+	VisibilityPublic   = "public"   // Listed for everybody to browse
+)
+
+// MaxRoomSize is the largest width and height of a room, in centimetres.
+const MaxRoomSize = 10_000
+
+// MaxPlacements is the largest number of placements in a room. Checking a
+// layout for overlaps compares every pair of placements.
+const MaxPlacements = 500
+
 type Room struct {
 	ID            int64           `json:"id"` // Unique integer ID for the Room
 	OwnerID       int64           `json:"-"`  // User ID who owns the Room
@@ -19,6 +32,10 @@ type Room struct {
 	Width         int64           `json:"width"`
 	Height        int64           `json:"height"`
 	FurnitureList []FurnitureList `json:"furniture_list,omitempty"` // Furniture inside room
+	Version       int             `json:"version"`                  // Incremented on every change to the room or its furniture
+	Visibility    string          `json:"visibility"`
+	Budget        *Money          `json:"budget,omitempty"` // Optional spending limit for the furniture
+	ThumbnailURL  string          `json:"thumbnail_url,omitempty"`
 }
 
 func ValidateRoom(v *validator.Validator, room *Room) {
@@ -28,25 +45,37 @@ func ValidateRoom(v *validator.Validator, room *Room) {
 	v.Check(len(room.Description) <= 400, "description", "must not be more than 400 bytes long")
 
 	v.Check(room.Width != 0, "width", "must be provided")
+	v.Check(room.Width > 0, "width", "must be positive number")
+	v.Check(room.Width <= MaxRoomSize, "width", fmt.Sprintf("must be a maximum of %d", MaxRoomSize))
 	v.Check(room.Height != 0, "height", "must be provided")
+	v.Check(room.Height > 0, "height", "must be positive number")
+	v.Check(room.Height <= MaxRoomSize, "height", fmt.Sprintf("must be a maximum of %d", MaxRoomSize))
+
+	v.Check(len(room.FurnitureList) <= MaxPlacements, "furniture_list",
+		fmt.Sprintf("must not contain more than %d placements", MaxPlacements))
+
+	v.Check(validator.In(room.Visibility, VisibilityPrivate, VisibilityUnlisted, VisibilityPublic),
+		"visibility", "must be one of private, unlisted or public")
+
+	v.Check(room.Budget == nil || *room.Budget >= 0, "budget", "must not be negative")
 }
 
 type RoomModel struct {
-	DB *sql.DB
+	DB DBTX
 }
 
 func (r RoomModel) Insert(room *Room) error {
 	query := `
-		INSERT INTO room (user_id, room_description, title, room_width, room_height)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING room_id, date`
+		INSERT INTO room (user_id, room_description, title, room_width, room_height, visibility, budget)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING room_id, date, version`
 
-	args := []interface{}{room.OwnerID, room.Description, room.Title, room.Width, room.Height}
+	args := []interface{}{room.OwnerID, room.Description, room.Title, room.Width, room.Height, room.Visibility, room.Budget}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return r.DB.QueryRowContext(ctx, query, args...).Scan(&room.ID, &room.Date)
+	return r.DB.QueryRowContext(ctx, query, args...).Scan(&room.ID, &room.Date, &room.Version)
 }
 
 func (r RoomModel) Get(id int64) (*Room, error) {
@@ -55,7 +84,7 @@ func (r RoomModel) Get(id int64) (*Room, error) {
 	}
 
 	query := `
-		SELECT room_id, user_id, date, room_description, title, room_width, room_height
+		SELECT room_id, user_id, date, room_description, title, room_width, room_height, version, visibility, budget
 		FROM room
 		WHERE room_id = $1`
 
@@ -72,6 +101,9 @@ func (r RoomModel) Get(id int64) (*Room, error) {
 		&room.Title,
 		&room.Width,
 		&room.Height,
+		&room.Version,
+		&room.Visibility,
+		&room.Budget,
 	)
 
 	if err != nil {
@@ -86,20 +118,32 @@ func (r RoomModel) Get(id int64) (*Room, error) {
 	return &room, nil
 }
 
-func (r RoomModel) GetAll(title string, width int, height int, filters Filters) ([]*Room, Metadata, error) {
+// GetAll lists the rooms matching the filters. A non-zero userID restricts the
+// result to the rooms the user owns or collaborates on, a non-empty visibility
+// to the rooms with that visibility. If overBudget is set, only rooms whose
+// furniture costs more than their budget are listed.
+func (r RoomModel) GetAll(userID int64, visibility string, title string, width int, height int, overBudget bool, filters Filters) ([]*Room, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), room_id, date, room_description, title, room_width, room_height
+		SELECT count(*) OVER(), room_id, user_id, date, room_description, title, room_width, room_height, version, visibility, budget
 		FROM room
 		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
 		AND (room_width <= $2 OR $2 = 0)
 		AND (room_height <= $3 OR $3 = 0)
+		AND (user_id = $4 OR $4 = 0
+			OR room_id IN (SELECT room_id FROM room_collaborators WHERE user_id = $4))
+		AND (visibility = $5 OR $5 = '')
+		AND (NOT $8 OR budget < (
+			SELECT COALESCE(SUM(f.price), 0)
+			FROM room_furniture rf
+			INNER JOIN furniture f ON f.furniture_id = rf.furniture_id
+			WHERE rf.room_id = room.room_id))
 		ORDER BY %s %s, room_id ASC
-		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $6 OFFSET $7`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{title, width, height, filters.limit(), filters.offset()}
+	args := []interface{}{title, width, height, userID, visibility, filters.limit(), filters.offset(), overBudget}
 
 	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -118,11 +162,15 @@ func (r RoomModel) GetAll(title string, width int, height int, filters Filters)
 		err := rows.Scan(
 			&totalRecords,
 			&room.ID,
+			&room.OwnerID,
 			&room.Date,
 			&room.Description,
 			&room.Title,
 			&room.Width,
 			&room.Height,
+			&room.Version,
+			&room.Visibility,
+			&room.Budget,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -140,41 +188,58 @@ func (r RoomModel) GetAll(title string, width int, height int, filters Filters)
 	return rooms, metadata, nil
 }
 
+// Update saves the room and increments its version. ErrEditConflict is
+// returned if the room was changed since it was read.
 func (r RoomModel) Update(room *Room) error {
 	query := `
 		UPDATE room
-		SET room_description = $1, title = $2, room_width = $3, room_height = $4
-		WHERE room_id = $5`
+		SET room_description = $1, title = $2, room_width = $3, room_height = $4,
+			visibility = $5, budget = $6, version = version + 1
+		WHERE room_id = $7 AND version = $8
+		RETURNING version`
 
 	args := []interface{}{
 		room.Description,
 		room.Title,
 		room.Width,
 		room.Height,
+		room.Visibility,
+		room.Budget,
 		room.ID,
+		room.Version,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := r.DB.ExecContext(ctx, query, args...)
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&room.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
 
-	return err
+	return nil
 }
 
-func (r RoomModel) Delete(id int64) error {
+// Delete removes the room if it is still at the given version, otherwise
+// ErrEditConflict is returned.
+func (r RoomModel) Delete(id int64, version int) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
 		DELETE FROM room
-		WHERE room_id = $1`
+		WHERE room_id = $1 AND version = $2`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := r.DB.ExecContext(ctx, query, id)
+	result, err := r.DB.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -185,7 +250,7 @@ func (r RoomModel) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		return ErrEditConflict
 	}
 	return nil
 }
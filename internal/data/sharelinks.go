@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ShareLink gives read-only access to a room to anybody holding its token,
+// without an EHome account. Only the hash of the token is stored.
+type ShareLink struct {
+	ID        int64     `json:"id"`
+	RoomID    int64     `json:"room_id"`
+	Plaintext string    `json:"token,omitempty"` // Only known right after the link was created
+	Hash      []byte    `json:"-"`
+	Scope     string    `json:"-"` // Always ScopeRoomShare
+	UserID    int64     `json:"-"` // User who created the link
+	Expiry    time.Time `json:"expiry"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ShareLinkModel struct {
+	DB DBTX
+}
+
+func (m ShareLinkModel) New(roomID, userID int64, ttl time.Duration) (*ShareLink, error) {
+	token, err := generateToken(userID, ttl, ScopeRoomShare)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &ShareLink{
+		RoomID:    roomID,
+		Plaintext: token.Plaintext,
+		Hash:      token.Hash,
+		Scope:     token.Scope,
+		UserID:    token.UserID,
+		Expiry:    token.Expiry,
+	}
+
+	err = m.Insert(link)
+	return link, err
+}
+
+func (m ShareLinkModel) Insert(link *ShareLink) error {
+	query := `
+		INSERT INTO room_share_links (hash, scope, room_id, user_id, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	args := []interface{}{link.Hash, link.Scope, link.RoomID, link.UserID, link.Expiry}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&link.ID, &link.CreatedAt)
+}
+
+// GetActiveForRoom returns the links of the room which are neither expired nor
+// revoked.
+func (m ShareLinkModel) GetActiveForRoom(roomID int64) ([]*ShareLink, error) {
+	query := `
+		SELECT id, room_id, user_id, expiry, created_at
+		FROM room_share_links
+		WHERE room_id = $1
+		AND revoked_at IS NULL
+		AND expiry > $2
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, roomID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	links := []*ShareLink{}
+
+	for rows.Next() {
+
+		var link ShareLink
+
+		err := rows.Scan(
+			&link.ID,
+			&link.RoomID,
+			&link.UserID,
+			&link.Expiry,
+			&link.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, &link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// GetRoomIDForToken returns the ID of the room an active link points to.
+func (m ShareLinkModel) GetRoomIDForToken(tokenPlaintext string) (int64, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT room_id
+		FROM room_share_links
+		WHERE hash = $1
+		AND scope = $2
+		AND revoked_at IS NULL
+		AND expiry > $3`
+
+	var roomID int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], ScopeRoomShare, time.Now()).Scan(&roomID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return roomID, nil
+}
+
+func (m ShareLinkModel) Revoke(roomID, id int64) error {
+	if roomID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		UPDATE room_share_links
+		SET revoked_at = NOW()
+		WHERE room_id = $1 AND id = $2 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, roomID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
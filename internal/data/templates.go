@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+// RoomTemplate is a curated room published by an admin, which users can
+// create their own rooms from. The layout is a snapshot of the room taken
+// when the template was published, so later changes to the room don't leak
+// into it.
+type RoomTemplate struct {
+	ID          int64     `json:"id"`
+	RoomID      *int64    `json:"room_id"` // Room the template was published from, nil once deleted
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Width       int64     `json:"width"`
+	Height      int64     `json:"height"`
+	CreatedBy   *int64    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	Room        *Room     `json:"-"` // Snapshot of the layout, only loaded for a single template
+}
+
+func ValidateTemplate(v *validator.Validator, template *RoomTemplate) {
+	v.Check(template.RoomID != nil && *template.RoomID > 0, "room_id", "must be provided")
+
+	v.Check(template.Name != "", "name", "must be provided")
+	// Rooms created from the template are named after it.
+	v.Check(len(template.Name) <= 30, "name", "must not be more than 30 bytes long")
+
+	v.Check(len(template.Description) <= 400, "description", "must not be more than 400 bytes long")
+}
+
+type TemplateModel struct {
+	DB DBTX
+}
+
+// Insert stores the template together with a snapshot of template.Room, which
+// must hold the room and its placements.
+func (m TemplateModel) Insert(template *RoomTemplate) error {
+	query := `
+		INSERT INTO room_templates (room_id, name, description, created_by, snapshot)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	snapshot, err := json.Marshal(template.Room)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{template.RoomID, template.Name, template.Description, template.CreatedBy, snapshot}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&template.ID, &template.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	template.Width = template.Room.Width
+	template.Height = template.Room.Height
+
+	return nil
+}
+
+func (m TemplateModel) Get(id int64) (*RoomTemplate, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, room_id, name, description, created_by, created_at, snapshot
+		FROM room_templates
+		WHERE id = $1`
+
+	var template RoomTemplate
+	var snapshot []byte
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&template.ID,
+		&template.RoomID,
+		&template.Name,
+		&template.Description,
+		&template.CreatedBy,
+		&template.CreatedAt,
+		&snapshot,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = json.Unmarshal(snapshot, &template.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Width = template.Room.Width
+	template.Height = template.Room.Height
+
+	return &template, nil
+}
+
+func (m TemplateModel) GetAll() ([]*RoomTemplate, error) {
+	query := `
+		SELECT id, room_id, name, description, (snapshot->>'width')::bigint,
+			(snapshot->>'height')::bigint, created_by, created_at
+		FROM room_templates
+		ORDER BY name, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	templates := []*RoomTemplate{}
+
+	for rows.Next() {
+
+		var template RoomTemplate
+
+		err := rows.Scan(
+			&template.ID,
+			&template.RoomID,
+			&template.Name,
+			&template.Description,
+			&template.Width,
+			&template.Height,
+			&template.CreatedBy,
+			&template.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, &template)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (m TemplateModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM room_templates
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Package dxf reads and writes the small subset of the AutoCAD DXF format
+// (release 12, ASCII) needed to exchange room plans with CAD tools.
+//
+// Coordinates are in DXF space, with the y axis pointing up, and angles are
+// counter-clockwise in degrees.
+package dxf
+
+import (
+	"strings"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// Layers of an exported room plan.
+const (
+	LayerRoom      = "ROOM"
+	LayerFurniture = "FURNITURE"
+)
+
+// Drawing is the content of a DXF file.
+type Drawing struct {
+	Outline geometry.Polygon // Room outline, nil if the file has none
+	Blocks  []Block
+	Inserts []Insert
+	Shapes  []Entity // Closed shapes outside blocks, other than the outline
+}
+
+// Block is a named shape which can be inserted many times. The shape is
+// relative to the base point of the block.
+type Block struct {
+	Name  string
+	Shape geometry.Shape
+}
+
+// Insert places a block.
+type Insert struct {
+	Block          string
+	Layer          string
+	X, Y           float64
+	ScaleX, ScaleY float64
+	Rotation       float64
+}
+
+// Entity is a closed shape, either a geometry.Polygon or a geometry.Circle.
+type Entity struct {
+	Layer string
+	Shape geometry.Shape
+}
+
+// Block returns the block with the given name, or nil.
+func (d *Drawing) Block(name string) *Block {
+	for i := range d.Blocks {
+		if strings.EqualFold(d.Blocks[i].Name, name) {
+			return &d.Blocks[i]
+		}
+	}
+	return nil
+}
+
+// BlockName turns s into a valid block name by replacing every character
+// other than letters, digits, '-' and '_' with '_'.
+func BlockName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,136 @@
+package dxf
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+func TestRoundTrip(t *testing.T) {
+	d := &Drawing{
+		Outline: geometry.Polygon{{X: 0, Y: 0}, {X: 500, Y: 0}, {X: 500, Y: 400}, {X: 0, Y: 400}},
+		Blocks: []Block{
+			{Name: "Sofa", Shape: geometry.Polygon{{X: -100, Y: -45}, {X: 100, Y: -45}, {X: 100, Y: 45}, {X: -100, Y: 45}}},
+			{Name: "Table", Shape: geometry.Circle{X: 0, Y: 0, Radius: 60}},
+		},
+		Inserts: []Insert{
+			{Block: "Sofa", Layer: LayerFurniture, X: 150.5, Y: 80, ScaleX: 1, ScaleY: 1, Rotation: 90},
+			{Block: "Table", Layer: LayerFurniture, X: 300, Y: 250.25, ScaleX: 2, ScaleY: 0.5},
+		},
+		Shapes: []Entity{
+			{Layer: "WALLS", Shape: geometry.Polygon{{X: -10, Y: -10}, {X: 510, Y: -10}, {X: 510, Y: 0}, {X: -10, Y: 0}}},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	err := Write(&buf, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, d) {
+		t.Errorf("Read(Write(d)) = %+v, want %+v", got, d)
+	}
+}
+
+func TestReadOutline(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities string
+		want     geometry.Polygon
+		shapes   int
+	}{
+		{
+			name:     "closed flag on the room layer",
+			entities: "0\nLWPOLYLINE\n8\nROOM\n70\n1\n10\n0\n20\n0\n10\n300\n20\n0\n10\n300\n20\n200\n",
+			want:     geometry.Polygon{{X: 0, Y: 0}, {X: 300, Y: 0}, {X: 300, Y: 200}},
+		},
+		{
+			name:     "closed by the last point",
+			entities: "0\nLWPOLYLINE\n8\nroom\n70\n0\n10\n0\n20\n0\n10\n300\n20\n0\n10\n300\n20\n200\n10\n0\n20\n0\n",
+			want:     geometry.Polygon{{X: 0, Y: 0}, {X: 300, Y: 0}, {X: 300, Y: 200}},
+		},
+		{
+			name: "largest polygon without a room layer",
+			entities: "0\nLWPOLYLINE\n8\nA\n70\n1\n10\n0\n20\n0\n10\n10\n20\n0\n10\n10\n20\n10\n" +
+				"0\nLWPOLYLINE\n8\nB\n70\n1\n10\n0\n20\n0\n10\n100\n20\n0\n10\n100\n20\n100\n",
+			want:   geometry.Polygon{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 100, Y: 100}},
+			shapes: 1,
+		},
+		{
+			name:     "open polyline",
+			entities: "0\nLWPOLYLINE\n8\nROOM\n70\n0\n10\n0\n20\n0\n10\n300\n20\n0\n10\n300\n20\n200\n",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		d, err := Read(strings.NewReader("0\nSECTION\n2\nENTITIES\n" + tt.entities + "0\nENDSEC\n0\nEOF\n"))
+		if err != nil {
+			t.Errorf("%s: Read error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(d.Outline, tt.want) {
+			t.Errorf("%s: Outline = %v, want %v", tt.name, d.Outline, tt.want)
+		}
+		if len(d.Shapes) != tt.shapes {
+			t.Errorf("%s: got %d shapes, want %d", tt.name, len(d.Shapes), tt.shapes)
+		}
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	polyline := func(x string) string {
+		return "0\nSECTION\n2\nENTITIES\n0\nLWPOLYLINE\n8\nROOM\n70\n1\n10\n" + x +
+			"\n20\n0\n10\n300\n20\n0\n10\n300\n20\n200\n0\nENDSEC\n0\nEOF\n"
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty file", ""},
+		{"binary DXF", "AutoCAD Binary DXF\r\n\x1a\x00"},
+		{"group code not a number", "0\nSECTION\nX\nENTITIES\n"},
+		{"truncated pair", "0\nSECTION\n2\nENTITIES\n0\nLWPOLYLINE\n8"},
+		{"NaN coordinate", polyline("NaN")},
+		{"infinite coordinate", polyline("+Inf")},
+		{"coordinate not a number", polyline("abc")},
+		{"no sections", "999\ncomment\n"},
+	}
+
+	for _, tt := range tests {
+		_, err := Read(strings.NewReader(tt.input))
+		if !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("%s: Read error = %v, want %v", tt.name, err, ErrInvalidFile)
+		}
+	}
+}
+
+func TestBlockName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Sofa", "Sofa"},
+		{"Corner sofa", "Corner_sofa"},
+		{"Desk-2_b", "Desk-2_b"},
+		{"Étagère", "_tag_re"},
+	}
+
+	for _, tt := range tests {
+		if got := BlockName(tt.input); got != tt.want {
+			t.Errorf("BlockName(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package dxf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+var ErrInvalidFile = errors.New("not a valid ASCII DXF file")
+
+type pair struct {
+	code  int
+	value string
+}
+
+// entity is the group of pairs from one code 0 pair up to the next.
+type entity struct {
+	kind  string
+	pairs []pair
+}
+
+func (e entity) str(code int) string {
+	for _, p := range e.pairs {
+		if p.code == code {
+			return p.value
+		}
+	}
+	return ""
+}
+
+func (e entity) float(code int, defaultValue float64) float64 {
+	for _, p := range e.pairs {
+		if p.code == code {
+			f, err := strconv.ParseFloat(p.value, 64)
+			if err != nil {
+				return defaultValue
+			}
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func (e entity) flags() int {
+	flags, _ := strconv.Atoi(e.str(70))
+	return flags
+}
+
+// Read parses an ASCII DXF file. Lines, polylines, circles and inserts are
+// read from the BLOCKS and ENTITIES sections, everything else is skipped. The
+// outline is the first closed polyline on the ROOM layer or, if there is none,
+// the largest closed polyline in the file.
+func Read(r io.Reader) (*Drawing, error) {
+	sections, err := readSections(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Drawing{}
+
+	blocks := sections["BLOCKS"]
+	for i := 0; i < len(blocks); i++ {
+		if blocks[i].kind != "BLOCK" {
+			continue
+		}
+
+		name := blocks[i].str(2)
+		baseX, baseY := blocks[i].float(10, 0), blocks[i].float(20, 0)
+
+		var shape geometry.Shape
+		for i++; i < len(blocks) && blocks[i].kind != "ENDBLK"; i++ {
+			s, next := readShape(blocks, i)
+			if s != nil && shape == nil {
+				shape = translate(s, -baseX, -baseY)
+			}
+			i = next
+		}
+
+		if shape != nil {
+			d.Blocks = append(d.Blocks, Block{Name: name, Shape: shape})
+		}
+	}
+
+	entities := sections["ENTITIES"]
+	for i := 0; i < len(entities); i++ {
+		e := entities[i]
+
+		if e.kind == "INSERT" {
+			d.Inserts = append(d.Inserts, Insert{
+				Block:    e.str(2),
+				Layer:    e.str(8),
+				X:        e.float(10, 0),
+				Y:        e.float(20, 0),
+				ScaleX:   e.float(41, 1),
+				ScaleY:   e.float(42, 1),
+				Rotation: e.float(50, 0),
+			})
+			continue
+		}
+
+		shape, next := readShape(entities, i)
+		if shape != nil {
+			d.Shapes = append(d.Shapes, Entity{Layer: e.str(8), Shape: shape})
+		}
+		i = next
+	}
+
+	d.takeOutline()
+
+	return d, nil
+}
+
+// takeOutline moves the room outline out of the shapes.
+func (d *Drawing) takeOutline() {
+	outline := -1
+
+	for i, e := range d.Shapes {
+		if _, ok := e.Shape.(geometry.Polygon); ok && strings.EqualFold(e.Layer, LayerRoom) {
+			outline = i
+			break
+		}
+	}
+
+	if outline == -1 {
+		largest := 0.0
+		for i, e := range d.Shapes {
+			if _, ok := e.Shape.(geometry.Polygon); !ok {
+				continue
+			}
+			b := e.Shape.Bounds()
+			if area := b.Width * b.Height; area > largest {
+				outline, largest = i, area
+			}
+		}
+	}
+
+	if outline != -1 {
+		d.Outline = d.Shapes[outline].Shape.(geometry.Polygon)
+		d.Shapes = append(d.Shapes[:outline], d.Shapes[outline+1:]...)
+	}
+}
+
+// readShape reads the closed shape starting at entities[i]. It returns nil for
+// anything else, and the index of the last entity which belongs to the shape.
+func readShape(entities []entity, i int) (geometry.Shape, int) {
+	e := entities[i]
+
+	switch e.kind {
+	case "CIRCLE":
+		return geometry.Circle{X: e.float(10, 0), Y: e.float(20, 0), Radius: e.float(40, 0)}, i
+
+	case "LWPOLYLINE":
+		var polygon geometry.Polygon
+		for _, p := range e.pairs {
+			switch p.code {
+			case 10:
+				x, _ := strconv.ParseFloat(p.value, 64)
+				polygon = append(polygon, geometry.Point{X: x})
+			case 20:
+				if len(polygon) > 0 {
+					polygon[len(polygon)-1].Y, _ = strconv.ParseFloat(p.value, 64)
+				}
+			}
+		}
+		return closedPolygon(polygon, e.flags()&1 != 0), i
+
+	case "POLYLINE":
+		var polygon geometry.Polygon
+		j := i + 1
+		for ; j < len(entities) && entities[j].kind == "VERTEX"; j++ {
+			polygon = append(polygon, geometry.Point{X: entities[j].float(10, 0), Y: entities[j].float(20, 0)})
+		}
+		if j < len(entities) && entities[j].kind == "SEQEND" {
+			j++
+		}
+		return closedPolygon(polygon, e.flags()&1 != 0), j - 1
+	}
+
+	return nil, i
+}
+
+// closedPolygon returns the polygon if it is closed, either by its flag or by
+// ending where it started, and nil otherwise.
+func closedPolygon(polygon geometry.Polygon, closedFlag bool) geometry.Shape {
+	if n := len(polygon); n > 3 && samePoint(polygon[0], polygon[n-1]) {
+		polygon = polygon[:n-1]
+		closedFlag = true
+	}
+
+	if !closedFlag || len(polygon) < 3 {
+		return nil
+	}
+
+	return polygon
+}
+
+func samePoint(a, b geometry.Point) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func translate(shape geometry.Shape, dx, dy float64) geometry.Shape {
+	switch s := shape.(type) {
+	case geometry.Circle:
+		s.X += dx
+		s.Y += dy
+		return s
+	case geometry.Polygon:
+		moved := make(geometry.Polygon, len(s))
+		for i, pt := range s {
+			moved[i] = geometry.Point{X: pt.X + dx, Y: pt.Y + dy}
+		}
+		return moved
+	}
+	return shape
+}
+
+// isFloatCode reports whether the group code is one of the ranges the DXF
+// reference reserves for floating point values, such as coordinates, radii and
+// angles.
+func isFloatCode(code int) bool {
+	return code >= 10 && code <= 59 || code >= 110 && code <= 149 ||
+		code >= 210 && code <= 239 || code >= 1010 && code <= 1059
+}
+
+// readSections splits the file into its sections, each a list of entities.
+// Values of floating point group codes must be finite numbers.
+func readSections(r io.Reader) (map[string][]entity, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	sections := map[string][]entity{}
+
+	var section string
+	var current *entity
+	line := 0
+
+	for {
+		if !scanner.Scan() {
+			break
+		}
+		line++
+
+		codeText := strings.TrimSpace(scanner.Text())
+		if line == 1 && strings.HasPrefix(codeText, "AutoCAD Binary DXF") {
+			return nil, fmt.Errorf("%w: binary DXF is not supported", ErrInvalidFile)
+		}
+
+		code, err := strconv.Atoi(codeText)
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected a group code on line %d", ErrInvalidFile, line)
+		}
+
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("%w: missing value for group code on line %d", ErrInvalidFile, line)
+		}
+		line++
+
+		value := strings.TrimSpace(scanner.Text())
+
+		if isFloatCode(code) {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("%w: expected a number on line %d", ErrInvalidFile, line)
+			}
+		}
+
+		if code != 0 {
+			if current != nil {
+				current.pairs = append(current.pairs, pair{code, value})
+			} else if code == 2 && section == "" {
+				section = strings.ToUpper(value)
+			}
+			continue
+		}
+
+		if current != nil && section != "" {
+			sections[section] = append(sections[section], *current)
+		}
+		current = nil
+
+		switch value {
+		case "SECTION":
+			section = ""
+		case "ENDSEC":
+			section = ""
+		case "EOF":
+			return sections, nil
+		default:
+			if section != "" {
+				current = &entity{kind: value}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(sections) == 0 {
+		return nil, ErrInvalidFile
+	}
+
+	return sections, nil
+}
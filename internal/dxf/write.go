@@ -0,0 +1,168 @@
+package dxf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) pair(code int, value string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, "%3d\n%s\n", code, value)
+}
+
+func (w *writer) float(code int, value float64) {
+	w.pair(code, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// Write writes the drawing as an ASCII DXF file. The outline goes on the ROOM
+// layer, inserts keep their own layer.
+func Write(out io.Writer, d *Drawing) error {
+	w := &writer{w: bufio.NewWriter(out)}
+
+	w.pair(0, "SECTION")
+	w.pair(2, "HEADER")
+	w.pair(9, "$ACADVER")
+	w.pair(1, "AC1009")
+	if d.Outline != nil {
+		b := d.Outline.Bounds()
+		w.pair(9, "$EXTMIN")
+		w.float(10, b.X)
+		w.float(20, b.Y)
+		w.pair(9, "$EXTMAX")
+		w.float(10, b.X+b.Width)
+		w.float(20, b.Y+b.Height)
+	}
+	w.pair(0, "ENDSEC")
+
+	w.pair(0, "SECTION")
+	w.pair(2, "TABLES")
+	w.pair(0, "TABLE")
+	w.pair(2, "LTYPE")
+	w.pair(70, "1")
+	w.pair(0, "LTYPE")
+	w.pair(2, "CONTINUOUS")
+	w.pair(70, "0")
+	w.pair(3, "Solid line")
+	w.pair(72, "65")
+	w.pair(73, "0")
+	w.float(40, 0)
+	w.pair(0, "ENDTAB")
+	w.pair(0, "TABLE")
+	w.pair(2, "LAYER")
+	w.pair(70, "2")
+	for _, layer := range []struct {
+		name  string
+		color string
+	}{{LayerRoom, "7"}, {LayerFurniture, "5"}} {
+		w.pair(0, "LAYER")
+		w.pair(2, layer.name)
+		w.pair(70, "0")
+		w.pair(62, layer.color)
+		w.pair(6, "CONTINUOUS")
+	}
+	w.pair(0, "ENDTAB")
+	w.pair(0, "ENDSEC")
+
+	w.pair(0, "SECTION")
+	w.pair(2, "BLOCKS")
+	for _, block := range d.Blocks {
+		w.pair(0, "BLOCK")
+		w.pair(8, "0")
+		w.pair(2, block.Name)
+		w.pair(70, "0")
+		w.float(10, 0)
+		w.float(20, 0)
+		w.float(30, 0)
+		w.pair(3, block.Name)
+		w.shape("0", block.Shape)
+		w.pair(0, "ENDBLK")
+		w.pair(8, "0")
+	}
+	w.pair(0, "ENDSEC")
+
+	w.pair(0, "SECTION")
+	w.pair(2, "ENTITIES")
+	if d.Outline != nil {
+		w.shape(LayerRoom, d.Outline)
+	}
+	for _, e := range d.Shapes {
+		w.shape(e.Layer, e.Shape)
+	}
+	for _, insert := range d.Inserts {
+		w.pair(0, "INSERT")
+		w.pair(8, insert.Layer)
+		w.pair(2, insert.Block)
+		w.float(10, insert.X)
+		w.float(20, insert.Y)
+		w.float(30, 0)
+		if insert.ScaleX != 0 && insert.ScaleX != 1 {
+			w.float(41, insert.ScaleX)
+		}
+		if insert.ScaleY != 0 && insert.ScaleY != 1 {
+			w.float(42, insert.ScaleY)
+		}
+		if insert.Rotation != 0 {
+			w.float(50, insert.Rotation)
+		}
+	}
+	w.pair(0, "ENDSEC")
+	w.pair(0, "EOF")
+
+	if w.err != nil {
+		return w.err
+	}
+
+	return w.w.Flush()
+}
+
+// shape writes circles as CIRCLE entities and everything else as a closed
+// POLYLINE, which every DXF reader understands.
+func (w *writer) shape(layer string, shape geometry.Shape) {
+	if c, ok := shape.(geometry.Circle); ok {
+		w.pair(0, "CIRCLE")
+		w.pair(8, layer)
+		w.float(10, c.X)
+		w.float(20, c.Y)
+		w.float(30, 0)
+		w.float(40, c.Radius)
+		return
+	}
+
+	var polygon geometry.Polygon
+	switch s := shape.(type) {
+	case geometry.Polygon:
+		polygon = s
+	case geometry.Rect:
+		polygon = s.Polygon()
+	default:
+		polygon = shape.Bounds().Polygon()
+	}
+
+	w.pair(0, "POLYLINE")
+	w.pair(8, layer)
+	w.pair(66, "1")
+	w.pair(70, "1")
+	w.float(10, 0)
+	w.float(20, 0)
+	w.float(30, 0)
+	for _, pt := range polygon {
+		w.pair(0, "VERTEX")
+		w.pair(8, layer)
+		w.float(10, pt.X)
+		w.float(20, pt.Y)
+		w.float(30, 0)
+	}
+	w.pair(0, "SEQEND")
+	w.pair(8, layer)
+}
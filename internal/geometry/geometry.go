@@ -0,0 +1,293 @@
+// Package geometry provides the intersection tests used to detect overlapping
+// furniture placements inside a room.
+package geometry
+
+import "math"
+
+// Shape is a closed two-dimensional figure.
+type Shape interface {
+	Bounds() Rect
+}
+
+// Rect is an axis-aligned rectangle with its top-left corner at (X, Y).
+type Rect struct {
+	X, Y          float64
+	Width, Height float64
+}
+
+// Bounds returns the rectangle itself.
+func (r Rect) Bounds() Rect {
+	return r
+}
+
+// Circle is a circle with its centre at (X, Y).
+type Circle struct {
+	X, Y   float64
+	Radius float64
+}
+
+// Bounds returns the smallest axis-aligned rectangle containing the circle.
+func (c Circle) Bounds() Rect {
+	return Rect{
+		X:      c.X - c.Radius,
+		Y:      c.Y - c.Radius,
+		Width:  2 * c.Radius,
+		Height: 2 * c.Radius,
+	}
+}
+
+// Point is a point on the plane.
+type Point struct {
+	X, Y float64
+}
+
+// Polygon is a convex polygon given by its vertices in order.
+type Polygon []Point
+
+// Bounds returns the smallest axis-aligned rectangle containing the polygon.
+func (p Polygon) Bounds() Rect {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, pt := range p {
+		minX = math.Min(minX, pt.X)
+		minY = math.Min(minY, pt.Y)
+		maxX = math.Max(maxX, pt.X)
+		maxY = math.Max(maxY, pt.Y)
+	}
+
+	return Rect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+// Contains reports whether the point lies inside the polygon. Unlike the
+// collision checks it also works for concave polygons, such as the outlines of
+// imported rooms.
+func (p Polygon) Contains(pt Point) bool {
+	inside := false
+
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) && pt.X < (b.X-a.X)*(pt.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// Area returns the area enclosed by the polygon, which must not cross itself.
+func (p Polygon) Area() float64 {
+	area := 0.0
+
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		area += (p[j].X + p[i].X) * (p[j].Y - p[i].Y)
+	}
+
+	return math.Abs(area) / 2
+}
+
+// Polygon returns the corners of the rectangle as a polygon.
+func (r Rect) Polygon() Polygon {
+	return Polygon{
+		{r.X, r.Y},
+		{r.X + r.Width, r.Y},
+		{r.X + r.Width, r.Y + r.Height},
+		{r.X, r.Y + r.Height},
+	}
+}
+
+// RotatedSize returns the width and height of the bounding box of a width x
+// height rectangle turned clockwise by deg degrees. Quarter turns simply swap
+// the width and height.
+func RotatedSize(width, height, deg float64) (float64, float64) {
+	deg = normalizeAngle(deg)
+
+	switch deg {
+	case 0, 180:
+		return width, height
+	case 90, 270:
+		return height, width
+	}
+
+	sin, cos := math.Sincos(deg * math.Pi / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+
+	return width*cos + height*sin, width*sin + height*cos
+}
+
+// RotatedRect returns a width x height rectangle centred at (cx, cy) and turned
+// clockwise by deg degrees around its centre. Quarter turns are returned as a
+// Rect, any other angle as a Polygon.
+func RotatedRect(cx, cy, width, height, deg float64) Shape {
+	deg = normalizeAngle(deg)
+
+	if math.Mod(deg, 90) == 0 {
+		w, h := RotatedSize(width, height, deg)
+		return Rect{X: cx - w/2, Y: cy - h/2, Width: w, Height: h}
+	}
+
+	sin, cos := math.Sincos(deg * math.Pi / 180)
+
+	corners := Rect{X: -width / 2, Y: -height / 2, Width: width, Height: height}.Polygon()
+	for i, pt := range corners {
+		// The y axis points down, so this turns the corner clockwise.
+		corners[i] = Point{
+			X: cx + pt.X*cos - pt.Y*sin,
+			Y: cy + pt.X*sin + pt.Y*cos,
+		}
+	}
+
+	return corners
+}
+
+func normalizeAngle(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// Intersects reports whether a and b overlap. Shapes which only touch along an
+// edge or at a single point are not considered to be overlapping.
+func Intersects(a, b Shape) bool {
+	switch a := a.(type) {
+	case Rect:
+		switch b := b.(type) {
+		case Rect:
+			return rectsIntersect(a, b)
+		case Circle:
+			return rectCircleIntersect(a, b)
+		case Polygon:
+			return polygonsIntersect(a.Polygon(), b)
+		}
+	case Circle:
+		switch b := b.(type) {
+		case Rect:
+			return rectCircleIntersect(b, a)
+		case Circle:
+			return circlesIntersect(a, b)
+		case Polygon:
+			return polygonCircleIntersect(b, a)
+		}
+	case Polygon:
+		switch b := b.(type) {
+		case Rect:
+			return polygonsIntersect(a, b.Polygon())
+		case Circle:
+			return polygonCircleIntersect(a, b)
+		case Polygon:
+			return polygonsIntersect(a, b)
+		}
+	}
+	panic("geometry: unsupported shape combination")
+}
+
+func rectsIntersect(a, b Rect) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width &&
+		a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+func circlesIntersect(a, b Circle) bool {
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	r := a.Radius + b.Radius
+	return dx*dx+dy*dy < r*r
+}
+
+func rectCircleIntersect(r Rect, c Circle) bool {
+	// Find the point of the rectangle closest to the circle centre and check
+	// whether it lies strictly inside the circle.
+	nx := clamp(c.X, r.X, r.X+r.Width)
+	ny := clamp(c.Y, r.Y, r.Y+r.Height)
+	dx := c.X - nx
+	dy := c.Y - ny
+	return dx*dx+dy*dy < c.Radius*c.Radius
+}
+
+// polygonsIntersect uses the separating axis theorem: two convex polygons
+// don't overlap if their projections on one of the edge normals are disjoint.
+func polygonsIntersect(a, b Polygon) bool {
+	for _, axis := range append(edgeNormals(a), edgeNormals(b)...) {
+		minA, maxA := a.project(axis)
+		minB, maxB := b.project(axis)
+		if maxA <= minB+epsilon || maxB <= minA+epsilon {
+			return false
+		}
+	}
+	return true
+}
+
+func polygonCircleIntersect(p Polygon, c Circle) bool {
+	axes := edgeNormals(p)
+
+	// The axis through the circle centre and the closest vertex separates the
+	// shapes when the circle is beside a corner rather than an edge.
+	closest := p[0]
+	for _, pt := range p[1:] {
+		if distanceSquared(pt, Point{c.X, c.Y}) < distanceSquared(closest, Point{c.X, c.Y}) {
+			closest = pt
+		}
+	}
+	if axis, ok := normalize(Point{c.X - closest.X, c.Y - closest.Y}); ok {
+		axes = append(axes, axis)
+	}
+
+	for _, axis := range axes {
+		minP, maxP := p.project(axis)
+		centre := c.X*axis.X + c.Y*axis.Y
+		if maxP <= centre-c.Radius+epsilon || centre+c.Radius <= minP+epsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// epsilon absorbs the rounding error of rotated corners, so that shapes which
+// merely touch aren't reported as overlapping.
+const epsilon = 1e-9
+
+func (p Polygon) project(axis Point) (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, pt := range p {
+		d := pt.X*axis.X + pt.Y*axis.Y
+		min = math.Min(min, d)
+		max = math.Max(max, d)
+	}
+	return min, max
+}
+
+func edgeNormals(p Polygon) []Point {
+	normals := make([]Point, 0, len(p))
+	for i := range p {
+		next := p[(i+1)%len(p)]
+		if normal, ok := normalize(Point{-(next.Y - p[i].Y), next.X - p[i].X}); ok {
+			normals = append(normals, normal)
+		}
+	}
+	return normals
+}
+
+func normalize(pt Point) (Point, bool) {
+	length := math.Hypot(pt.X, pt.Y)
+	if length == 0 {
+		return Point{}, false
+	}
+	return Point{pt.X / length, pt.Y / length}, true
+}
+
+func distanceSquared(a, b Point) float64 {
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	return dx*dx + dy*dy
+}
+
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntersects(t *testing.T) {
+	square := Rect{X: 0, Y: 0, Width: 10, Height: 10}
+
+	// A 10 x 10 square turned by 45 degrees, with its corners 5√2 away from
+	// the centre on the axes.
+	diamond := RotatedRect(0, 0, 10, 10, 45)
+	half := 5 * math.Sqrt2
+
+	tests := []struct {
+		name string
+		a, b Shape
+		want bool
+	}{
+		{"rects overlapping", square, Rect{X: 5, Y: 5, Width: 10, Height: 10}, true},
+		{"rects touching edges", square, Rect{X: 10, Y: 0, Width: 10, Height: 10}, false},
+		{"rects touching corners", square, Rect{X: 10, Y: 10, Width: 10, Height: 10}, false},
+		{"rects apart", square, Rect{X: 11, Y: 0, Width: 10, Height: 10}, false},
+		{"rect inside rect", square, Rect{X: 2, Y: 2, Width: 2, Height: 2}, true},
+
+		{"circles overlapping", Circle{X: 0, Y: 0, Radius: 5}, Circle{X: 9, Y: 0, Radius: 5}, true},
+		{"circles touching", Circle{X: 0, Y: 0, Radius: 5}, Circle{X: 10, Y: 0, Radius: 5}, false},
+
+		{"circle touching rect edge", square, Circle{X: 15, Y: 5, Radius: 5}, false},
+		{"circle overlapping rect edge", square, Circle{X: 14, Y: 5, Radius: 5}, true},
+		{"circle beside rect corner", square, Circle{X: 12, Y: 12, Radius: 2.5}, false},
+		{"circle over rect corner", square, Circle{X: 12, Y: 12, Radius: 3}, true},
+
+		{"rotated rect overlapping rect", diamond, Rect{X: half - 0.1, Y: -1, Width: 5, Height: 2}, true},
+		{"rotated rect beside rect", diamond, Rect{X: half + 0.1, Y: -1, Width: 5, Height: 2}, false},
+		{"rotated rect corner in rect gap", diamond, Rect{X: 4, Y: 4, Width: 5, Height: 5}, false},
+		{"rotated rects sharing an edge", diamond, RotatedRect(half, half, 10, 10, 45), false},
+		{"rotated rects overlapping", diamond, RotatedRect(7, 7, 10, 10, 45), true},
+		{"rotated rects at different angles", RotatedRect(0, 0, 20, 4, 30), RotatedRect(0, 0, 20, 4, 120), true},
+		{"rotated rects side by side", RotatedRect(0, 0, 20, 4, 30), RotatedRect(-2, 2*math.Sqrt(3), 20, 4, 30), false},
+
+		{"polygon touching polygon edges", square.Polygon(), Rect{X: 10, Y: 0, Width: 10, Height: 10}.Polygon(), false},
+		{"polygon overlapping polygon", square.Polygon(), Rect{X: 9, Y: 0, Width: 10, Height: 10}.Polygon(), true},
+
+		{"circle touching polygon edge", square.Polygon(), Circle{X: 15, Y: 5, Radius: 5}, false},
+		{"circle overlapping polygon edge", square.Polygon(), Circle{X: 14, Y: 5, Radius: 5}, true},
+		{"circle beside polygon corner", square.Polygon(), Circle{X: 12, Y: 12, Radius: 2.5}, false},
+		{"circle over polygon corner", square.Polygon(), Circle{X: 12, Y: 12, Radius: 3}, true},
+		{"circle beside rotated rect corner", diamond, Circle{X: half + 2, Y: 0, Radius: 1.9}, false},
+		{"circle over rotated rect corner", diamond, Circle{X: half + 2, Y: 0, Radius: 2.1}, true},
+		{"circle inside polygon", square.Polygon(), Circle{X: 5, Y: 5, Radius: 1}, true},
+	}
+
+	for _, tt := range tests {
+		if got := Intersects(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Intersects(a, b) = %t, want %t", tt.name, got, tt.want)
+		}
+		if got := Intersects(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s: Intersects(b, a) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIntersectsEpsilon(t *testing.T) {
+	square := Rect{X: 0, Y: 0, Width: 10, Height: 10}.Polygon()
+
+	tests := []struct {
+		name    string
+		overlap float64
+		want    bool
+	}{
+		{"rounding error", epsilon / 10, false},
+		{"beyond epsilon", 1e-6, true},
+	}
+
+	for _, tt := range tests {
+		other := Rect{X: 10 - tt.overlap, Y: 0, Width: 10, Height: 10}.Polygon()
+		if got := polygonsIntersect(square, other); got != tt.want {
+			t.Errorf("%s: polygonsIntersect = %t, want %t", tt.name, got, tt.want)
+		}
+
+		circle := Circle{X: 15 - tt.overlap, Y: 5, Radius: 5}
+		if got := polygonCircleIntersect(square, circle); got != tt.want {
+			t.Errorf("%s: polygonCircleIntersect = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRotatedSize(t *testing.T) {
+	tests := []struct {
+		width, height, deg float64
+		wantW, wantH       float64
+	}{
+		{20, 10, 0, 20, 10},
+		{20, 10, 90, 10, 20},
+		{20, 10, 180, 20, 10},
+		{20, 10, -90, 10, 20},
+		{20, 10, 450, 10, 20},
+		{10, 10, 45, 10 * math.Sqrt2, 10 * math.Sqrt2},
+	}
+
+	for _, tt := range tests {
+		w, h := RotatedSize(tt.width, tt.height, tt.deg)
+		if math.Abs(w-tt.wantW) > 1e-9 || math.Abs(h-tt.wantH) > 1e-9 {
+			t.Errorf("RotatedSize(%g, %g, %g) = %g, %g, want %g, %g", tt.width, tt.height, tt.deg, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
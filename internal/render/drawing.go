@@ -0,0 +1,65 @@
+package render
+
+import (
+	"image/color"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// Drawing is a floor plan laid out as a list of primitive elements, which the
+// output formats render in order. Coordinates are in room units with the
+// origin in the top-left corner and the y axis pointing down.
+type Drawing struct {
+	Width, Height float64
+	Elements      []Element
+}
+
+// Element is one of Figure, Line or Text.
+type Element interface {
+	element()
+}
+
+// Style describes how figures and lines are painted. A transparent Fill or
+// Stroke isn't painted at all.
+type Style struct {
+	Fill        color.RGBA
+	Stroke      color.RGBA
+	StrokeWidth float64
+	Dashed      bool
+}
+
+// Figure is a filled and outlined shape.
+type Figure struct {
+	Shape geometry.Shape
+	Style Style
+}
+
+// Line is a straight line segment.
+type Line struct {
+	From, To geometry.Point
+	Style    Style
+}
+
+// Anchor sets which part of a Text is placed at its position.
+type Anchor int
+
+const (
+	AnchorStart Anchor = iota
+	AnchorMiddle
+	AnchorEnd
+)
+
+// Text is a single line of text. At is the anchor point on the baseline.
+// Vertical text runs bottom to top.
+type Text struct {
+	At       geometry.Point
+	Text     string
+	Size     float64
+	Anchor   Anchor
+	Color    color.RGBA
+	Vertical bool
+}
+
+func (Figure) element() {}
+func (Line) element()   {}
+func (Text) element()   {}
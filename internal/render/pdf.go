@@ -0,0 +1,444 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// Sheet is the printed page around a floor plan: a title block with the
+// details of the room and a schedule of the furniture in it.
+type Sheet struct {
+	Title    string
+	Details  []Detail
+	Schedule Table
+}
+
+// Detail is a labelled line of the title block.
+type Detail struct {
+	Label, Value string
+}
+
+// Table is a simple table. Columns after the first are right aligned, which
+// suits quantities and prices. An empty Footer is left out.
+type Table struct {
+	Columns []string
+	Widths  []float64 // Relative column widths
+	Rows    [][]string
+	Footer  []string
+}
+
+// A4 landscape, in points.
+const (
+	pageWidth   = 842.0
+	pageHeight  = 595.0
+	pageMargin  = 36.0
+	sideColumn  = 250.0
+	columnGap   = 18.0
+	detailSize  = 9.0
+	tableSize   = 8.0
+	titleSize   = 16.0
+	lineSpacing = 1.35
+)
+
+// WritePDF writes a single-page PDF with the drawing scaled to fit the left
+// part of the page and the sheet in a column on the right. The standard
+// Helvetica fonts are used, so text outside the Windows-1252 character set is
+// replaced.
+func WritePDF(w io.Writer, d *Drawing, sheet *Sheet) error {
+	p := &pdfPage{}
+
+	p.drawing(d, geometry.Rect{
+		X:      pageMargin,
+		Y:      pageMargin,
+		Width:  pageWidth - 2*pageMargin - sideColumn - columnGap,
+		Height: pageHeight - 2*pageMargin,
+	})
+
+	p.sheet(sheet, pageWidth-pageMargin-sideColumn, pageMargin, sideColumn)
+
+	return writePDFDocument(w, p.content.Bytes(), sheet.Title)
+}
+
+// pdfPage collects the content stream of the page. Its methods take top-down
+// page coordinates in points, like the rest of the package, and flip them.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (p *pdfPage) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&p.content, format, args...)
+	p.content.WriteByte('\n')
+}
+
+// drawing scales the drawing into the box, keeping its aspect ratio.
+func (p *pdfPage) drawing(d *Drawing, box geometry.Rect) {
+	scale := math.Min(box.Width/d.Width, box.Height/d.Height)
+
+	tr := func(pt geometry.Point) geometry.Point {
+		return geometry.Point{X: box.X + pt.X*scale, Y: box.Y + pt.Y*scale}
+	}
+
+	for _, e := range d.Elements {
+		switch e := e.(type) {
+		case Figure:
+			p.shape(e.Shape, tr, scale, e.Style)
+		case Line:
+			p.path([]geometry.Point{tr(e.From), tr(e.To)}, false)
+			p.paint(e.Style, scale, false)
+		case Text:
+			p.text(tr(e.At), e.Text, "F1", e.Size*scale, e.Anchor, e.Color, e.Vertical)
+		}
+	}
+}
+
+func (p *pdfPage) shape(shape geometry.Shape, tr func(geometry.Point) geometry.Point, scale float64, style Style) {
+	switch s := shape.(type) {
+	case geometry.Circle:
+		// Four Bézier curves are close enough to a circle on paper.
+		const k = 0.5523
+		c := tr(geometry.Point{X: s.X, Y: s.Y})
+		r := s.Radius * scale
+		x, y := c.X, pageHeight-c.Y
+
+		p.printf("%s %s m", pdfNum(x+r), pdfNum(y))
+		p.printf("%s %s %s %s %s %s c", pdfNum(x+r), pdfNum(y+k*r), pdfNum(x+k*r), pdfNum(y+r), pdfNum(x), pdfNum(y+r))
+		p.printf("%s %s %s %s %s %s c", pdfNum(x-k*r), pdfNum(y+r), pdfNum(x-r), pdfNum(y+k*r), pdfNum(x-r), pdfNum(y))
+		p.printf("%s %s %s %s %s %s c", pdfNum(x-r), pdfNum(y-k*r), pdfNum(x-k*r), pdfNum(y-r), pdfNum(x), pdfNum(y-r))
+		p.printf("%s %s %s %s %s %s c h", pdfNum(x+k*r), pdfNum(y-r), pdfNum(x+r), pdfNum(y-k*r), pdfNum(x+r), pdfNum(y))
+	default:
+		outline := outlineOf(shape, 1)
+		points := make([]geometry.Point, len(outline))
+		for i, pt := range outline {
+			points[i] = tr(pt)
+		}
+		p.path(points, true)
+	}
+
+	p.paint(style, scale, true)
+}
+
+func (p *pdfPage) path(points []geometry.Point, closed bool) {
+	for i, pt := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		p.printf("%s %s %s", pdfNum(pt.X), pdfNum(pageHeight-pt.Y), op)
+	}
+	if closed {
+		p.printf("h")
+	}
+}
+
+// paint fills and strokes the current path. Nothing visible still has to
+// end the path.
+func (p *pdfPage) paint(style Style, scale float64, fillable bool) {
+	fill := fillable && style.Fill.A != 0
+	stroke := style.Stroke.A != 0 && style.StrokeWidth > 0
+
+	if fill {
+		p.printf("%s rg", pdfColor(style.Fill))
+	}
+
+	if stroke {
+		p.printf("%s RG %s w", pdfColor(style.Stroke), pdfNum(math.Max(style.StrokeWidth*scale, 0.25)))
+		if style.Dashed {
+			p.printf("[%s] 0 d", pdfNum(style.StrokeWidth*scale*4))
+		} else {
+			p.printf("[] 0 d")
+		}
+	}
+
+	switch {
+	case fill && stroke:
+		p.printf("B")
+	case fill:
+		p.printf("f")
+	case stroke:
+		p.printf("S")
+	default:
+		p.printf("n")
+	}
+}
+
+func (p *pdfPage) text(at geometry.Point, text, font string, size float64, anchor Anchor, c color.RGBA, vertical bool) {
+	offset := 0.0
+	switch anchor {
+	case AnchorMiddle:
+		offset = textWidth(text, size) / 2
+	case AnchorEnd:
+		offset = textWidth(text, size)
+	}
+
+	x, y := at.X, pageHeight-at.Y
+
+	p.printf("BT /%s %s Tf %s rg", font, pdfNum(size), pdfColor(c))
+	if vertical {
+		p.printf("0 1 -1 0 %s %s Tm", pdfNum(x), pdfNum(y-offset))
+	} else {
+		p.printf("1 0 0 1 %s %s Tm", pdfNum(x-offset), pdfNum(y))
+	}
+	p.printf("(%s) Tj ET", pdfString(text))
+}
+
+// sheet draws the title block and the schedule into a column of the given
+// width, starting at the top of the page.
+func (p *pdfPage) sheet(sheet *Sheet, x, y, width float64) {
+	y += titleSize
+	for _, line := range wrapText(sheet.Title, titleSize, width) {
+		p.text(geometry.Point{X: x, Y: y}, line, "F2", titleSize, AnchorStart, black, false)
+		y += titleSize * lineSpacing
+	}
+
+	y += detailSize / 2
+	labelWidth := width * 0.3
+
+	for _, detail := range sheet.Details {
+		p.text(geometry.Point{X: x, Y: y}, detail.Label, "F2", detailSize, AnchorStart, grey, false)
+		for _, line := range wrapText(detail.Value, detailSize, width-labelWidth) {
+			p.text(geometry.Point{X: x + labelWidth, Y: y}, line, "F1", detailSize, AnchorStart, black, false)
+			y += detailSize * lineSpacing
+		}
+	}
+
+	y += detailSize * 2
+
+	p.table(&sheet.Schedule, x, y, width, pageHeight-pageMargin)
+}
+
+// table draws the table until it runs out of room at bottom. Rows which don't
+// fit are summarized in a final row.
+func (p *pdfPage) table(t *Table, x, y, width, bottom float64) {
+	if len(t.Columns) == 0 {
+		return
+	}
+
+	var total float64
+	for i := range t.Columns {
+		total += columnWidth(t, i)
+	}
+
+	lefts := make([]float64, len(t.Columns))
+	rights := make([]float64, len(t.Columns))
+	left := x
+	for i := range t.Columns {
+		lefts[i] = left
+		left += columnWidth(t, i) / total * width
+		rights[i] = left
+	}
+
+	row := tableSize * 1.8
+	rule := Style{Stroke: grey, StrokeWidth: 0.5}
+
+	cells := func(values []string, font string) {
+		for i, value := range values {
+			if i >= len(t.Columns) {
+				break
+			}
+			if i == 0 {
+				value = fitText(value, tableSize, rights[0]-lefts[0]-tableSize/2)
+				p.text(geometry.Point{X: lefts[i], Y: y}, value, font, tableSize, AnchorStart, black, false)
+			} else {
+				p.text(geometry.Point{X: rights[i], Y: y}, value, font, tableSize, AnchorEnd, black, false)
+			}
+		}
+		y += row
+	}
+
+	hline := func() {
+		ruleY := y - row + tableSize*0.5
+		p.path([]geometry.Point{{X: x, Y: ruleY}, {X: x + width, Y: ruleY}}, false)
+		p.paint(rule, 1, false)
+	}
+
+	cells(t.Columns, "F2")
+	hline()
+
+	// Keep room for the summary and the footer.
+	reserved := row
+	if len(t.Footer) > 0 {
+		reserved += row
+	}
+
+	for i, values := range t.Rows {
+		if y+reserved > bottom && i < len(t.Rows)-1 {
+			cells([]string{fmt.Sprintf("… and %d more", len(t.Rows)-i)}, "F1")
+			break
+		}
+		cells(values, "F1")
+	}
+
+	if len(t.Footer) > 0 {
+		y += row * 0.2
+		hline()
+		y += row * 0.2
+		cells(t.Footer, "F2")
+	}
+}
+
+func columnWidth(t *Table, i int) float64 {
+	if i < len(t.Widths) && t.Widths[i] > 0 {
+		return t.Widths[i]
+	}
+	return 1
+}
+
+// writePDFDocument wraps the page content into a complete PDF file.
+func writePDFDocument(w io.Writer, content []byte, title string) error {
+	var compressed bytes.Buffer
+
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(content); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents 4 0 R "+
+			"/Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>", pdfNum(pageWidth), pdfNum(pageHeight)),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (EHome) >>", pdfString(title)),
+	}
+
+	bw := bufio.NewWriter(w)
+	offset := 0
+	offsets := make([]int, len(objects))
+
+	write := func(s string) {
+		n, _ := bw.WriteString(s)
+		offset += n
+	}
+
+	write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	for i, obj := range objects {
+		offsets[i] = offset
+		write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, obj))
+	}
+
+	xref := offset
+	write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1))
+	for _, off := range offsets {
+		write(fmt.Sprintf("%010d 00000 n \n", off))
+	}
+
+	write(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%EOF\n",
+		len(objects)+1, len(objects), xref))
+
+	return bw.Flush()
+}
+
+func pdfNum(f float64) string {
+	return num(f)
+}
+
+func pdfColor(c color.RGBA) string {
+	return fmt.Sprintf("%s %s %s", pdfNum(float64(c.R)/0xff), pdfNum(float64(c.G)/0xff), pdfNum(float64(c.B)/0xff))
+}
+
+// winAnsi maps the characters of Windows-1252 outside Latin-1 to their codes.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// pdfString encodes the text as a literal string in WinAnsiEncoding.
+func pdfString(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case winAnsi[r] != 0:
+			fmt.Fprintf(&b, "\\%03o", winAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
+// helveticaWidths are the advance widths of the printable ASCII characters in
+// Helvetica, in thousandths of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// textWidth estimates the width of the text in points. The bold face is a
+// little wider, which only matters for centred text and is ignored.
+func textWidth(s string, size float64) float64 {
+	var width int
+	for _, r := range s {
+		if r >= 0x20 && r < 0x7f {
+			width += helveticaWidths[r-0x20]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// wrapText breaks the text into lines no wider than width.
+func wrapText(s string, size, width float64) []string {
+	var lines []string
+
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && textWidth(candidate, size) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// fitText shortens the text with an ellipsis until it is no wider than width.
+func fitText(s string, size, width float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes) + "…"
+}
@@ -0,0 +1,209 @@
+// Package render draws room floor plans. A Plan is laid out once into a
+// Drawing, which can then be written out in the supported image formats.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// Plan is a room and the furniture placed inside it, in room units.
+type Plan struct {
+	Title         string
+	Width, Height float64
+	Items         []Item
+}
+
+// Item is a single placement. Items with the same Group share a colour and a
+// legend entry.
+type Item struct {
+	Label         string
+	Group         int64
+	Shape         geometry.Shape // Footprint of the placement inside the room
+	Width, Height float64        // Size of the unrotated item
+}
+
+// MaxGridLines bounds the number of grid lines in a drawing. Finer grids are
+// drawn with a wider spacing, so that a tiny spacing on a huge room can't
+// blow up the drawing.
+const MaxGridLines = 500
+
+// Options selects the optional parts of the drawing.
+type Options struct {
+	Grid        bool
+	GridSpacing float64 // Distance between grid lines in room units, widened to respect MaxGridLines
+	Dimensions  bool    // Annotate the room and item dimensions
+	Legend      bool
+}
+
+var (
+	black       = color.RGBA{0x21, 0x21, 0x21, 0xff}
+	grey        = color.RGBA{0x75, 0x75, 0x75, 0xff}
+	gridGrey    = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	floor       = color.RGBA{0xfa, 0xfa, 0xfa, 0xff}
+	white       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	itemPalette = []color.RGBA{
+		{0x90, 0xca, 0xf9, 0xff},
+		{0xa5, 0xd6, 0xa7, 0xff},
+		{0xff, 0xcc, 0x80, 0xff},
+		{0xce, 0x93, 0xd8, 0xff},
+		{0xef, 0x9a, 0x9a, 0xff},
+		{0x80, 0xde, 0xea, 0xff},
+		{0xe6, 0xee, 0x9c, 0xff},
+		{0xbc, 0xaa, 0xa4, 0xff},
+	}
+)
+
+type legendEntry struct {
+	item  Item
+	color color.RGBA
+	count int
+}
+
+// Layout positions the room, its furniture and the requested annotations.
+func Layout(plan *Plan, opts Options) *Drawing {
+	unit := math.Max(math.Max(plan.Width, plan.Height), 1)
+	fontSize := unit / 40
+	stroke := fontSize / 8
+
+	margin := fontSize * 2
+	if opts.Dimensions {
+		margin = fontSize * 4
+	}
+
+	ox, oy := margin, margin
+	if plan.Title != "" {
+		oy += fontSize * 2
+	}
+
+	d := &Drawing{Width: plan.Width + 2*margin}
+
+	if plan.Title != "" {
+		d.add(Text{At: geometry.Point{X: ox, Y: fontSize * 2}, Text: plan.Title, Size: fontSize * 1.4, Color: black})
+	}
+
+	room := geometry.Rect{X: ox, Y: oy, Width: plan.Width, Height: plan.Height}
+	d.add(Figure{Shape: room, Style: Style{Fill: floor}})
+
+	if opts.Grid && opts.GridSpacing > 0 {
+		spacing := math.Max(opts.GridSpacing, (plan.Width+plan.Height)/MaxGridLines)
+
+		style := Style{Stroke: gridGrey, StrokeWidth: stroke / 2}
+		for x := spacing; x < plan.Width; x += spacing {
+			d.add(Line{From: geometry.Point{X: ox + x, Y: oy}, To: geometry.Point{X: ox + x, Y: oy + plan.Height}, Style: style})
+		}
+		for y := spacing; y < plan.Height; y += spacing {
+			d.add(Line{From: geometry.Point{X: ox, Y: oy + y}, To: geometry.Point{X: ox + plan.Width, Y: oy + y}, Style: style})
+		}
+	}
+
+	d.add(Figure{Shape: room, Style: Style{Stroke: black, StrokeWidth: stroke * 2}})
+
+	var legend []*legendEntry
+	groups := map[int64]*legendEntry{}
+
+	for _, item := range plan.Items {
+		entry, ok := groups[item.Group]
+		if !ok {
+			entry = &legendEntry{item: item, color: itemPalette[len(legend)%len(itemPalette)]}
+			groups[item.Group] = entry
+			legend = append(legend, entry)
+		}
+		entry.count++
+
+		shape := translate(item.Shape, ox, oy)
+		d.add(Figure{Shape: shape, Style: Style{Fill: entry.color, Stroke: grey, StrokeWidth: stroke}})
+
+		bounds := shape.Bounds()
+		centre := geometry.Point{X: bounds.X + bounds.Width/2, Y: bounds.Y + bounds.Height/2}
+
+		// Shrink the label until it roughly fits inside the item.
+		size := math.Min(fontSize, 1.6*bounds.Width/math.Max(float64(len(item.Label)), 1))
+
+		if opts.Dimensions {
+			d.add(Text{At: geometry.Point{X: centre.X, Y: centre.Y}, Text: item.Label, Size: size, Anchor: AnchorMiddle, Color: black})
+			d.add(Text{At: geometry.Point{X: centre.X, Y: centre.Y + size}, Text: sizeLabel(item.Width, item.Height), Size: size * 0.8, Anchor: AnchorMiddle, Color: grey})
+		} else {
+			d.add(Text{At: geometry.Point{X: centre.X, Y: centre.Y + size/3}, Text: item.Label, Size: size, Anchor: AnchorMiddle, Color: black})
+		}
+	}
+
+	if opts.Dimensions {
+		d.addDimensions(room, margin, fontSize, stroke)
+	}
+
+	bottom := oy + plan.Height + margin
+
+	if opts.Legend && len(legend) > 0 {
+		row := fontSize * 1.6
+		for i, entry := range legend {
+			y := bottom + float64(i)*row
+			d.add(Figure{
+				Shape: geometry.Rect{X: ox, Y: y, Width: fontSize, Height: fontSize},
+				Style: Style{Fill: entry.color, Stroke: grey, StrokeWidth: stroke},
+			})
+
+			text := fmt.Sprintf("%s, %s × %d", entry.item.Label, sizeLabel(entry.item.Width, entry.item.Height), entry.count)
+			d.add(Text{At: geometry.Point{X: ox + fontSize*1.5, Y: y + fontSize*0.85}, Text: text, Size: fontSize, Color: black})
+		}
+		bottom += float64(len(legend))*row + margin/2
+	}
+
+	d.Height = bottom
+
+	return d
+}
+
+// addDimensions draws the width of the room above it and the height to its
+// left, halfway into the margin.
+func (d *Drawing) addDimensions(room geometry.Rect, margin, fontSize, stroke float64) {
+	style := Style{Stroke: grey, StrokeWidth: stroke}
+	tick := fontSize / 2
+
+	y := room.Y - margin/2
+	d.add(Line{From: geometry.Point{X: room.X, Y: y}, To: geometry.Point{X: room.X + room.Width, Y: y}, Style: style})
+	d.add(Line{From: geometry.Point{X: room.X, Y: y - tick}, To: geometry.Point{X: room.X, Y: y + tick}, Style: style})
+	d.add(Line{From: geometry.Point{X: room.X + room.Width, Y: y - tick}, To: geometry.Point{X: room.X + room.Width, Y: y + tick}, Style: style})
+	d.add(Text{At: geometry.Point{X: room.X + room.Width/2, Y: y - tick}, Text: formatLength(room.Width), Size: fontSize, Anchor: AnchorMiddle, Color: black})
+
+	x := room.X - margin/2
+	d.add(Line{From: geometry.Point{X: x, Y: room.Y}, To: geometry.Point{X: x, Y: room.Y + room.Height}, Style: style})
+	d.add(Line{From: geometry.Point{X: x - tick, Y: room.Y}, To: geometry.Point{X: x + tick, Y: room.Y}, Style: style})
+	d.add(Line{From: geometry.Point{X: x - tick, Y: room.Y + room.Height}, To: geometry.Point{X: x + tick, Y: room.Y + room.Height}, Style: style})
+	d.add(Text{At: geometry.Point{X: x - tick, Y: room.Y + room.Height/2}, Text: formatLength(room.Height), Size: fontSize, Anchor: AnchorMiddle, Color: black, Vertical: true})
+}
+
+func (d *Drawing) add(e Element) {
+	d.Elements = append(d.Elements, e)
+}
+
+func translate(shape geometry.Shape, dx, dy float64) geometry.Shape {
+	switch s := shape.(type) {
+	case geometry.Rect:
+		s.X += dx
+		s.Y += dy
+		return s
+	case geometry.Circle:
+		s.X += dx
+		s.Y += dy
+		return s
+	case geometry.Polygon:
+		moved := make(geometry.Polygon, len(s))
+		for i, pt := range s {
+			moved[i] = geometry.Point{X: pt.X + dx, Y: pt.Y + dy}
+		}
+		return moved
+	}
+	return shape
+}
+
+func sizeLabel(width, height float64) string {
+	return formatLength(width) + "×" + formatLength(height)
+}
+
+func formatLength(length float64) string {
+	return fmt.Sprintf("%g", math.Round(length*100)/100)
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// samples is the number of sub-scanlines per pixel row used for anti-aliasing.
+const samples = 4
+
+// MaxPixels bounds the area of a rasterized drawing. Narrow, deep rooms would
+// otherwise need huge images even at modest widths.
+const MaxPixels = 16_000_000
+
+var ErrImageTooLarge = errors.New("image would be too large")
+
+// PixelHeight returns the height of the image of the drawing rasterized
+// pixelWidth pixels wide.
+func (d *Drawing) PixelHeight(pixelWidth int) int {
+	return int(math.Ceil(d.Height * float64(pixelWidth) / d.Width))
+}
+
+// WritePNG rasterizes the drawing pixelWidth pixels wide and writes it as a
+// PNG image. Text elements are skipped: the standard library has no font
+// rasterizer, and labels would be unreadable at thumbnail sizes anyway.
+func (d *Drawing) WritePNG(w io.Writer, pixelWidth int) error {
+	img, err := d.Raster(pixelWidth)
+	if err != nil {
+		return err
+	}
+
+	return png.Encode(w, img)
+}
+
+// Raster draws the drawing into a new image pixelWidth pixels wide, keeping
+// the aspect ratio. It returns ErrImageTooLarge rather than allocating an
+// image of more than MaxPixels pixels.
+func (d *Drawing) Raster(pixelWidth int) (*image.RGBA, error) {
+	scale := float64(pixelWidth) / d.Width
+	pixelHeight := d.PixelHeight(pixelWidth)
+
+	if int64(pixelWidth)*int64(pixelHeight) > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, pixelWidth, pixelHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	r := rasterizer{img: img, scale: scale}
+
+	for _, e := range d.Elements {
+		switch e := e.(type) {
+		case Figure:
+			outline := outlineOf(e.Shape, scale)
+			if e.Style.Fill.A != 0 {
+				r.fill(outline, e.Style.Fill)
+			}
+			if e.Style.Stroke.A != 0 && e.Style.StrokeWidth > 0 {
+				for i := range outline {
+					r.stroke(outline[i], outline[(i+1)%len(outline)], e.Style)
+				}
+			}
+		case Line:
+			if e.Style.Stroke.A != 0 && e.Style.StrokeWidth > 0 {
+				r.stroke(e.From, e.To, e.Style)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// outlineOf approximates the shape with a polygon. Circles get enough
+// vertices for the segments to stay around two pixels long.
+func outlineOf(shape geometry.Shape, scale float64) geometry.Polygon {
+	switch s := shape.(type) {
+	case geometry.Rect:
+		return s.Polygon()
+	case geometry.Circle:
+		n := int(math.Max(16, math.Pi*s.Radius*scale))
+		outline := make(geometry.Polygon, n)
+		for i := range outline {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+			outline[i] = geometry.Point{X: s.X + s.Radius*cos, Y: s.Y + s.Radius*sin}
+		}
+		return outline
+	case geometry.Polygon:
+		return s
+	}
+	return shape.Bounds().Polygon()
+}
+
+type rasterizer struct {
+	img   *image.RGBA
+	scale float64
+}
+
+// stroke draws the segment as a rectangle StrokeWidth wide, extended by half
+// the width at both ends so that the corners of outlines are closed.
+func (r rasterizer) stroke(from, to geometry.Point, style Style) {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+
+	// Hairlines would disappear when scaled down, keep them a pixel wide.
+	half := math.Max(style.StrokeWidth, 1/r.scale) / 2
+	ux, uy := dx/length*half, dy/length*half
+
+	r.fill(geometry.Polygon{
+		{X: from.X - ux - uy, Y: from.Y - uy + ux},
+		{X: to.X + ux - uy, Y: to.Y + uy + ux},
+		{X: to.X + ux + uy, Y: to.Y + uy - ux},
+		{X: from.X - ux + uy, Y: from.Y - uy - ux},
+	}, style.Stroke)
+}
+
+// fill paints the polygon, given in drawing units, with the even-odd rule.
+// Every pixel is blended according to how much of it the polygon covers.
+func (r rasterizer) fill(polygon geometry.Polygon, c color.RGBA) {
+	if len(polygon) < 3 {
+		return
+	}
+
+	pts := make([]geometry.Point, len(polygon))
+	for i, pt := range polygon {
+		pts[i] = geometry.Point{X: pt.X * r.scale, Y: pt.Y * r.scale}
+	}
+
+	bounds := r.img.Bounds()
+	box := geometry.Polygon(pts).Bounds()
+
+	minX := clampInt(int(math.Floor(box.X)), bounds.Min.X, bounds.Max.X)
+	maxX := clampInt(int(math.Ceil(box.X+box.Width)), bounds.Min.X, bounds.Max.X)
+	minY := clampInt(int(math.Floor(box.Y)), bounds.Min.Y, bounds.Max.Y)
+	maxY := clampInt(int(math.Ceil(box.Y+box.Height)), bounds.Min.Y, bounds.Max.Y)
+
+	if minX >= maxX || minY >= maxY {
+		return
+	}
+
+	coverage := make([]float64, maxX-minX)
+	var crossings []float64
+
+	for py := minY; py < maxY; py++ {
+		for i := range coverage {
+			coverage[i] = 0
+		}
+
+		for s := 0; s < samples; s++ {
+			y := float64(py) + (float64(s)+0.5)/samples
+
+			crossings = crossings[:0]
+			for i, a := range pts {
+				b := pts[(i+1)%len(pts)]
+				if (a.Y <= y && y < b.Y) || (b.Y <= y && y < a.Y) {
+					crossings = append(crossings, a.X+(y-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+				}
+			}
+
+			sort.Float64s(crossings)
+
+			for i := 0; i+1 < len(crossings); i += 2 {
+				addSpan(coverage, crossings[i]-float64(minX), crossings[i+1]-float64(minX))
+			}
+		}
+
+		for i, cov := range coverage {
+			if cov > 0 {
+				blend(r.img, minX+i, py, c, math.Min(cov/samples, 1))
+			}
+		}
+	}
+}
+
+// addSpan adds the part of every pixel between x0 and x1 to its coverage.
+func addSpan(coverage []float64, x0, x1 float64) {
+	x0 = math.Max(x0, 0)
+	x1 = math.Min(x1, float64(len(coverage)))
+
+	for px := int(x0); px < len(coverage) && float64(px) < x1; px++ {
+		coverage[px] += math.Min(x1, float64(px+1)) - math.Max(x0, float64(px))
+	}
+}
+
+func blend(img *image.RGBA, x, y int, c color.RGBA, coverage float64) {
+	alpha := coverage * float64(c.A) / 0xff
+	i := img.PixOffset(x, y)
+
+	for j, v := range []uint8{c.R, c.G, c.B} {
+		dst := float64(img.Pix[i+j])
+		img.Pix[i+j] = uint8(math.Round(float64(v)*alpha + dst*(1-alpha)))
+	}
+}
+
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
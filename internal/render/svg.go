@@ -0,0 +1,106 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// WriteSVG writes the drawing as an SVG document. A non-zero pixelWidth sets
+// the rendered width of the image, otherwise one room unit is one pixel.
+func (d *Drawing) WriteSVG(w io.Writer, pixelWidth float64) error {
+	if pixelWidth <= 0 {
+		pixelWidth = d.Width
+	}
+	pixelHeight := pixelWidth * d.Height / d.Width
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s" font-family="sans-serif">`+"\n",
+		num(pixelWidth), num(pixelHeight), num(d.Width), num(d.Height))
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(white))
+
+	for _, e := range d.Elements {
+		switch e := e.(type) {
+		case Figure:
+			writeSVGShape(bw, e.Shape, e.Style)
+		case Line:
+			fmt.Fprintf(bw, `<line x1="%s" y1="%s" x2="%s" y2="%s"%s/>`+"\n",
+				num(e.From.X), num(e.From.Y), num(e.To.X), num(e.To.Y), styleAttrs(e.Style))
+		case Text:
+			writeSVGText(bw, e)
+		}
+	}
+
+	fmt.Fprint(bw, "</svg>\n")
+
+	return bw.Flush()
+}
+
+func writeSVGShape(w io.Writer, shape geometry.Shape, style Style) {
+	switch s := shape.(type) {
+	case geometry.Rect:
+		fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s"%s/>`+"\n",
+			num(s.X), num(s.Y), num(s.Width), num(s.Height), styleAttrs(style))
+	case geometry.Circle:
+		fmt.Fprintf(w, `<circle cx="%s" cy="%s" r="%s"%s/>`+"\n",
+			num(s.X), num(s.Y), num(s.Radius), styleAttrs(style))
+	case geometry.Polygon:
+		points := make([]string, len(s))
+		for i, pt := range s {
+			points[i] = num(pt.X) + "," + num(pt.Y)
+		}
+		fmt.Fprintf(w, `<polygon points="%s"%s/>`+"\n", strings.Join(points, " "), styleAttrs(style))
+	}
+}
+
+func writeSVGText(w io.Writer, t Text) {
+	anchor := "start"
+	switch t.Anchor {
+	case AnchorMiddle:
+		anchor = "middle"
+	case AnchorEnd:
+		anchor = "end"
+	}
+
+	transform := ""
+	if t.Vertical {
+		transform = fmt.Sprintf(` transform="rotate(-90 %s %s)"`, num(t.At.X), num(t.At.Y))
+	}
+
+	fmt.Fprintf(w, `<text x="%s" y="%s" font-size="%s" text-anchor="%s" fill="%s"%s>%s</text>`+"\n",
+		num(t.At.X), num(t.At.Y), num(t.Size), anchor, hexColor(t.Color), transform, html.EscapeString(t.Text))
+}
+
+func styleAttrs(style Style) string {
+	var b strings.Builder
+
+	if style.Fill.A == 0 {
+		b.WriteString(` fill="none"`)
+	} else {
+		fmt.Fprintf(&b, ` fill="%s"`, hexColor(style.Fill))
+	}
+
+	if style.Stroke.A != 0 && style.StrokeWidth > 0 {
+		fmt.Fprintf(&b, ` stroke="%s" stroke-width="%s"`, hexColor(style.Stroke), num(style.StrokeWidth))
+		if style.Dashed {
+			fmt.Fprintf(&b, ` stroke-dasharray="%s"`, num(style.StrokeWidth*4))
+		}
+	}
+
+	return b.String()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// num formats a coordinate without needless digits.
+func num(f float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", f), "0"), ".")
+}
@@ -0,0 +1,50 @@
+// Package roomdoc defines the EHome room document, a self-contained JSON
+// description of a room which can be exported from one account or server and
+// imported into another.
+//
+// A document of the current version 2 looks like this:
+//
+//	{
+//		"format": "ehome-room",
+//		"format_version": 2,
+//		"exported_at": "2022-05-01T12:00:00Z",
+//		"room": {
+//			"title": "Living room",
+//			"description": "Ground floor",
+//			"width": 500,
+//			"height": 400,
+//			"budget": 1500.00
+//		},
+//		"furniture": [
+//			{
+//				"ref": "f1",
+//				"catalog_id": 12,
+//				"name": "Sofa",
+//				"description": "Three seats",
+//				"price": 499.99,
+//				"width": 200,
+//				"height": 90,
+//				"shape": "rectangle",
+//				"image": "sofa.png"
+//			}
+//		],
+//		"placements": [
+//			{"furniture": "f1", "x": 10, "y": 20, "rotation": 90}
+//		]
+//	}
+//
+// Lengths are whole centimetres. The room spans from (0, 0) to (width,
+// height) with the y axis pointing down. "furniture" lists every item used in
+// the room once; "ref" identifies the item within the document and is what
+// placements refer to. "catalog_id" is the ID of the item on the exporting
+// server and a hint only: an import uses it when the item there still has the
+// same name and size, and otherwise looks the item up by name and size. The
+// shape is "rectangle" or "circle". A placement gives the top left corner of
+// the footprint of the item after turning it clockwise by rotation degrees.
+// Prices and the optional budget are decimal numbers with at most two
+// decimals.
+//
+// Version 1 is the plain room as returned by GET /v1/rooms/:id, with or
+// without the enclosing {"room": ...} object. Such documents only know the
+// catalog IDs of their furniture; Parse migrates them to the current version.
+package roomdoc
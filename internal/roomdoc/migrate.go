@@ -0,0 +1,95 @@
+package roomdoc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// upgrades turn a document of the version given by the key into one of the
+// next version. Every change to the format adds an entry here.
+var upgrades = map[int]func(map[string]json.RawMessage) (map[string]json.RawMessage, error){
+	1: upgradeV1,
+}
+
+// upgradeV1 turns a room as written by the API into a version 2 document.
+func upgradeV1(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	body := raw
+	if room, ok := raw["room"]; ok {
+		body = nil
+		if json.Unmarshal(room, &body) != nil || body == nil {
+			return nil, ErrInvalidDocument
+		}
+	}
+
+	js, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy struct {
+		Title         *string         `json:"title"`
+		Description   string          `json:"description"`
+		Width         int64           `json:"width"`
+		Height        int64           `json:"height"`
+		Budget        json.RawMessage `json:"budget"`
+		FurnitureList []struct {
+			FurnitureID int64   `json:"furniture_id"`
+			X           int64   `json:"x"`
+			Y           int64   `json:"y"`
+			Rotation    float64 `json:"rotation"`
+		} `json:"furniture_list"`
+	}
+
+	if json.Unmarshal(js, &legacy) != nil || legacy.Title == nil {
+		return nil, ErrInvalidDocument
+	}
+
+	room := map[string]interface{}{
+		"title":       *legacy.Title,
+		"description": legacy.Description,
+		"width":       legacy.Width,
+		"height":      legacy.Height,
+	}
+
+	if len(legacy.Budget) > 0 {
+		room["budget"] = legacy.Budget
+	}
+
+	furniture := []map[string]interface{}{}
+	placements := []map[string]interface{}{}
+	refs := map[int64]string{}
+
+	for _, val := range legacy.FurnitureList {
+		ref, ok := refs[val.FurnitureID]
+		if !ok {
+			ref = fmt.Sprintf("f%d", len(refs)+1)
+			refs[val.FurnitureID] = ref
+			furniture = append(furniture, map[string]interface{}{"ref": ref, "catalog_id": val.FurnitureID})
+		}
+
+		placements = append(placements, map[string]interface{}{
+			"furniture": ref,
+			"x":         val.X,
+			"y":         val.Y,
+			"rotation":  val.Rotation,
+		})
+	}
+
+	doc := map[string]interface{}{
+		"format":         Format,
+		"format_version": 2,
+		"room":           room,
+		"furniture":      furniture,
+		"placements":     placements,
+	}
+
+	js, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var upgraded map[string]json.RawMessage
+	err = json.Unmarshal(js, &upgraded)
+
+	return upgraded, err
+}
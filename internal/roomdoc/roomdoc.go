@@ -0,0 +1,201 @@
+package roomdoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+const (
+	Format  = "ehome-room"
+	Version = 2
+)
+
+// Shapes of document furniture.
+const (
+	ShapeRectangle = "rectangle"
+	ShapeCircle    = "circle"
+)
+
+var (
+	ErrInvalidDocument    = errors.New("not an EHome room document")
+	ErrUnsupportedVersion = fmt.Errorf("room document version is newer than %d", Version)
+)
+
+type Document struct {
+	Format        string      `json:"format"`
+	FormatVersion int         `json:"format_version"`
+	ExportedAt    time.Time   `json:"exported_at"`
+	Room          Room        `json:"room"`
+	Furniture     []Furniture `json:"furniture"`
+	Placements    []Placement `json:"placements"`
+}
+
+type Room struct {
+	Title       string      `json:"title"`
+	Description string      `json:"description,omitempty"`
+	Width       int64       `json:"width"`
+	Height      int64       `json:"height"`
+	Budget      *data.Money `json:"budget,omitempty"`
+}
+
+// Furniture is an item used in the room. Documents migrated from version 1
+// only have the ref and the catalog ID.
+type Furniture struct {
+	Ref         string     `json:"ref"`
+	CatalogID   int64      `json:"catalog_id,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Price       data.Money `json:"price,omitempty"`
+	Width       int64      `json:"width,omitempty"`
+	Height      int64      `json:"height,omitempty"`
+	Shape       string     `json:"shape,omitempty"`
+	Image       string     `json:"image,omitempty"`
+}
+
+// HasSpec reports whether the item is described by more than its catalog ID.
+func (f *Furniture) HasSpec() bool {
+	return f.Name != ""
+}
+
+type Placement struct {
+	Furniture string  `json:"furniture"` // Ref of the item
+	X         int64   `json:"x"`
+	Y         int64   `json:"y"`
+	Rotation  float64 `json:"rotation"` // Clockwise, in degrees
+}
+
+// New describes the room and its placements. Placements of items missing
+// from furniture are left out.
+func New(room *data.Room, furniture map[int64]*data.Furniture, exportedAt time.Time) *Document {
+	doc := &Document{
+		Format:        Format,
+		FormatVersion: Version,
+		ExportedAt:    exportedAt.UTC(),
+		Room: Room{
+			Title:       room.Title,
+			Description: room.Description,
+			Width:       room.Width,
+			Height:      room.Height,
+			Budget:      room.Budget,
+		},
+		Furniture:  []Furniture{},
+		Placements: []Placement{},
+	}
+
+	refs := map[int64]string{}
+
+	for _, val := range room.FurnitureList {
+		f, ok := furniture[val.FurnitureID]
+		if !ok {
+			continue
+		}
+
+		ref, ok := refs[f.ID]
+		if !ok {
+			ref = fmt.Sprintf("f%d", len(refs)+1)
+			refs[f.ID] = ref
+
+			shape := ShapeRectangle
+			if f.Shape == data.Circle {
+				shape = ShapeCircle
+			}
+
+			doc.Furniture = append(doc.Furniture, Furniture{
+				Ref:         ref,
+				CatalogID:   f.ID,
+				Name:        f.Name,
+				Description: f.Description,
+				Price:       f.Price,
+				Width:       f.Width,
+				Height:      f.Height,
+				Shape:       shape,
+				Image:       f.Image,
+			})
+		}
+
+		doc.Placements = append(doc.Placements, Placement{
+			Furniture: ref,
+			X:         val.X,
+			Y:         val.Y,
+			Rotation:  val.Rotation,
+		})
+	}
+
+	return doc
+}
+
+// Parse reads a document of any version, migrating it to the current one.
+func Parse(r io.Reader) (*Document, error) {
+	var raw map[string]json.RawMessage
+
+	err := json.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return nil, ErrInvalidDocument
+	}
+
+	version := 1
+
+	if format, ok := raw["format"]; ok {
+		var name string
+		if json.Unmarshal(format, &name) != nil || name != Format {
+			return nil, ErrInvalidDocument
+		}
+
+		if json.Unmarshal(raw["format_version"], &version) != nil || version < 1 {
+			return nil, ErrInvalidDocument
+		}
+
+		if version > Version {
+			return nil, ErrUnsupportedVersion
+		}
+	}
+
+	for ; version < Version; version++ {
+		raw, err = upgrades[version](raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	js, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc Document
+
+	err = json.Unmarshal(js, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDocument, err)
+	}
+
+	return &doc, nil
+}
+
+// Validate checks the parts of the document that can't be checked like a
+// newly created room, such as references between items and placements.
+func Validate(v *validator.Validator, doc *Document) {
+	refs := make(map[string]bool, len(doc.Furniture))
+
+	for i, f := range doc.Furniture {
+		key := fmt.Sprintf("furniture[%d]", i)
+
+		v.Check(f.Ref != "", key+".ref", "must be provided")
+		v.Check(!refs[f.Ref], key+".ref", "must be unique")
+		refs[f.Ref] = true
+
+		v.Check(f.CatalogID != 0 || f.HasSpec(), key, "must have a catalog_id or a name")
+		v.Check(f.Shape == "" || validator.In(f.Shape, ShapeRectangle, ShapeCircle),
+			key+".shape", "must be rectangle or circle")
+	}
+
+	for i, p := range doc.Placements {
+		v.Check(refs[p.Furniture], fmt.Sprintf("placements[%d].furniture", i), "must be the ref of an item in furniture")
+	}
+}
@@ -0,0 +1,199 @@
+package roomdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WrastAct/EHome/internal/data"
+	"github.com/WrastAct/EHome/internal/validator"
+)
+
+func TestRoundTrip(t *testing.T) {
+	budget := data.Money(150000)
+
+	room := &data.Room{
+		Title:       "Living room",
+		Description: "Ground floor",
+		Width:       500,
+		Height:      400,
+		Budget:      &budget,
+		FurnitureList: []data.FurnitureList{
+			{FurnitureID: 12, X: 10, Y: 20, Rotation: 90},
+			{FurnitureID: 7, X: 300, Y: 200},
+			{FurnitureID: 12, X: 10, Y: 200},
+			{FurnitureID: 99, X: 0, Y: 0}, // Left out, not in the furniture map
+		},
+	}
+
+	furniture := map[int64]*data.Furniture{
+		12: {ID: 12, Name: "Sofa", Description: "Three seats", Price: 49999, Width: 200, Height: 90, Image: "sofa.png"},
+		7:  {ID: 7, Name: "Table", Price: 12000, Width: 80, Height: 80, Shape: data.Circle},
+	}
+
+	doc := New(room, furniture, time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	want := &Document{
+		Format:        Format,
+		FormatVersion: Version,
+		ExportedAt:    time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC),
+		Room:          Room{Title: "Living room", Description: "Ground floor", Width: 500, Height: 400, Budget: &budget},
+		Furniture: []Furniture{
+			{Ref: "f1", CatalogID: 12, Name: "Sofa", Description: "Three seats", Price: 49999, Width: 200, Height: 90, Shape: ShapeRectangle, Image: "sofa.png"},
+			{Ref: "f2", CatalogID: 7, Name: "Table", Price: 12000, Width: 80, Height: 80, Shape: ShapeCircle},
+		},
+		Placements: []Placement{
+			{Furniture: "f1", X: 10, Y: 20, Rotation: 90},
+			{Furniture: "f2", X: 300, Y: 200},
+			{Furniture: "f1", X: 10, Y: 200},
+		},
+	}
+
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("New = %+v, want %+v", doc, want)
+	}
+
+	js, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Parse(bytes.NewReader(js))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(New) = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseVersion1(t *testing.T) {
+	budget := data.Money(50000)
+
+	want := &Document{
+		Format:        Format,
+		FormatVersion: Version,
+		Room:          Room{Title: "Study", Width: 300, Height: 250, Budget: &budget},
+		Furniture: []Furniture{
+			{Ref: "f1", CatalogID: 4},
+			{Ref: "f2", CatalogID: 9},
+		},
+		Placements: []Placement{
+			{Furniture: "f1", X: 0, Y: 0},
+			{Furniture: "f2", X: 100, Y: 50, Rotation: 270},
+			{Furniture: "f1", X: 200, Y: 0},
+		},
+	}
+
+	room := `{"id": 3, "title": "Study", "width": 300, "height": 250, "budget": 500.00, "version": 7,
+		"furniture_list": [
+			{"id": 1, "furniture_id": 4, "x": 0, "y": 0, "rotation": 0},
+			{"id": 2, "furniture_id": 9, "x": 100, "y": 50, "rotation": 270},
+			{"id": 3, "furniture_id": 4, "x": 200, "y": 0, "rotation": 0}
+		]}`
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"plain room", room},
+		{"enveloped room", `{"room": ` + room + `}`},
+		{"explicit version 1", `{"format": "ehome-room", "format_version": 1, "room": ` + room + `}`},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%s: Parse error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Parse = %+v, want %+v", tt.name, got, want)
+		}
+	}
+
+	got, err := Parse(strings.NewReader(`{"title": "Empty", "width": 100, "height": 100}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Furniture) != 0 || len(got.Placements) != 0 || got.Room.Budget != nil {
+		t.Errorf("Parse of an empty room = %+v, want no furniture, placements or budget", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"not JSON", `<room/>`, ErrInvalidDocument},
+		{"truncated", `{"format": "ehome-room", "format_version": 2, "room": {`, ErrInvalidDocument},
+		{"array", `[]`, ErrInvalidDocument},
+		{"other format", `{"format": "sweethome", "format_version": 2}`, ErrInvalidDocument},
+		{"format not a string", `{"format": 2, "format_version": 2}`, ErrInvalidDocument},
+		{"missing version", `{"format": "ehome-room"}`, ErrInvalidDocument},
+		{"version zero", `{"format": "ehome-room", "format_version": 0}`, ErrInvalidDocument},
+		{"newer version", `{"format": "ehome-room", "format_version": 3}`, ErrUnsupportedVersion},
+		{"version 1 without title", `{"width": 100, "height": 100}`, ErrInvalidDocument},
+		{"version 1 room not an object", `{"room": "Study"}`, ErrInvalidDocument},
+		{"wrong field type", `{"format": "ehome-room", "format_version": 2, "room": {"title": 5}}`, ErrInvalidDocument},
+		{"price with three decimals", `{"format": "ehome-room", "format_version": 2, "furniture": [{"ref": "f1", "price": 1.005}]}`, ErrInvalidDocument},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Parse error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  Document
+		want map[string]string
+	}{
+		{
+			name: "valid",
+			doc: Document{
+				Furniture:  []Furniture{{Ref: "f1", CatalogID: 3}, {Ref: "f2", Name: "Lamp", Shape: ShapeCircle}},
+				Placements: []Placement{{Furniture: "f1"}, {Furniture: "f2"}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "broken references",
+			doc: Document{
+				Furniture: []Furniture{
+					{Ref: "", CatalogID: 3},
+					{Ref: "f1", CatalogID: 3},
+					{Ref: "f1", Name: "Lamp", Shape: "triangle"},
+					{Ref: "f3"},
+				},
+				Placements: []Placement{{Furniture: "f1"}, {Furniture: "f9"}},
+			},
+			want: map[string]string{
+				"furniture[0].ref":        "must be provided",
+				"furniture[2].ref":        "must be unique",
+				"furniture[2].shape":      "must be rectangle or circle",
+				"furniture[3]":            "must have a catalog_id or a name",
+				"placements[1].furniture": "must be the ref of an item in furniture",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		Validate(v, &tt.doc)
+		if !reflect.DeepEqual(v.Errors, tt.want) {
+			t.Errorf("%s: Validate errors = %v, want %v", tt.name, v.Errors, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package sh3d
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// Read reads a .sh3d file of the given size. Furniture groups are flattened
+// into their pieces.
+func Read(r io.ReaderAt, size int64) (*Home, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	var entry *zip.File
+	hasHome := false
+
+	for _, f := range archive.File {
+		switch f.Name {
+		case homeEntry:
+			entry = f
+		case "Home":
+			hasHome = true
+		}
+	}
+
+	if entry == nil {
+		if hasHome {
+			return nil, ErrNoHomeXML
+		}
+		return nil, ErrInvalidFile
+	}
+
+	if entry.UncompressedSize64 > MaxHomeXMLSize {
+		return nil, fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidFile, homeEntry, MaxHomeXMLSize)
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	defer rc.Close()
+
+	// The size in the header isn't checked against the data, so the reader
+	// is cut off as well. A truncated Home.xml fails to parse.
+	return readHome(io.LimitReader(rc, MaxHomeXMLSize))
+}
+
+// attrs gives access to the attributes of an element.
+type attrs []xml.Attr
+
+func (a attrs) str(name string) string {
+	for _, attr := range a {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func (a attrs) float(name string, defaultValue float64) (float64, error) {
+	value := a.str(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%w: %s is not a number", ErrInvalidFile, name)
+	}
+
+	return f, nil
+}
+
+// floats parses several attributes at once, stopping at the first error.
+func (a attrs) floats(names []string, values ...*float64) error {
+	for i, name := range names {
+		f, err := a.float(name, *values[i])
+		if err != nil {
+			return err
+		}
+		*values[i] = f
+	}
+	return nil
+}
+
+func readHome(r io.Reader) (*Home, error) {
+	decoder := xml.NewDecoder(r)
+
+	home := &Home{}
+
+	var room *Room
+	var groups []string // Levels of the furniture groups being read
+
+	seenHome := false
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidFile
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			a := attrs(t.Attr)
+
+			if !seenHome {
+				if t.Name.Local != "home" {
+					return nil, ErrInvalidFile
+				}
+				seenHome = true
+				home.Name = a.str("name")
+				home.WallHeight, err = a.float("wallHeight", 0)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			switch t.Name.Local {
+			case "room":
+				if len(home.Rooms) == MaxRooms {
+					return nil, fmt.Errorf("%w: more than %d rooms", ErrInvalidFile, MaxRooms)
+				}
+
+				room = &Room{Name: a.str("name"), Level: a.str("level")}
+
+			case "point":
+				if room == nil {
+					continue
+				}
+
+				if len(room.Points) == MaxRoomPoints {
+					return nil, fmt.Errorf("%w: a room has more than %d points", ErrInvalidFile, MaxRoomPoints)
+				}
+
+				var pt geometry.Point
+				err := a.floats([]string{"x", "y"}, &pt.X, &pt.Y)
+				if err != nil {
+					return nil, err
+				}
+
+				room.Points = append(room.Points, pt)
+
+			case "wall":
+				if len(home.Walls) == MaxWalls {
+					return nil, fmt.Errorf("%w: more than %d walls", ErrInvalidFile, MaxWalls)
+				}
+
+				w := Wall{Level: a.str("level")}
+
+				err := a.floats([]string{"xStart", "yStart", "xEnd", "yEnd", "thickness", "height"},
+					&w.XStart, &w.YStart, &w.XEnd, &w.YEnd, &w.Thickness, &w.Height)
+				if err != nil {
+					return nil, err
+				}
+
+				home.Walls = append(home.Walls, w)
+
+			case "furnitureGroup":
+				level := a.str("level")
+				if level == "" && len(groups) > 0 {
+					level = groups[len(groups)-1]
+				}
+				groups = append(groups, level)
+
+			case "pieceOfFurniture", "light", "doorOrWindow":
+				if len(home.Pieces) == MaxPieces {
+					return nil, fmt.Errorf("%w: more than %d pieces of furniture", ErrInvalidFile, MaxPieces)
+				}
+
+				p := Piece{
+					Name:         a.str("name"),
+					Level:        a.str("level"),
+					Price:        a.str("price"),
+					DoorOrWindow: t.Name.Local == "doorOrWindow",
+					Visible:      a.str("visible") != "false",
+				}
+
+				if p.Level == "" && len(groups) > 0 {
+					p.Level = groups[len(groups)-1]
+				}
+
+				var angle float64
+				err := a.floats([]string{"x", "y", "width", "depth", "height", "angle"},
+					&p.X, &p.Y, &p.Width, &p.Depth, &p.Height, &angle)
+				if err != nil {
+					return nil, err
+				}
+
+				// Angles are stored in single precision, so a quarter turn
+				// would come out a few millionths of a degree off.
+				p.Angle = math.Round(angle*180/math.Pi*1000) / 1000
+
+				home.Pieces = append(home.Pieces, p)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "room":
+				if room != nil && len(room.Points) >= 3 {
+					home.Rooms = append(home.Rooms, *room)
+				}
+				room = nil
+
+			case "furnitureGroup":
+				if len(groups) > 0 {
+					groups = groups[:len(groups)-1]
+				}
+			}
+		}
+	}
+
+	if !seenHome {
+		return nil, ErrInvalidFile
+	}
+
+	return home, nil
+}
@@ -0,0 +1,70 @@
+// Package sh3d reads and writes the parts of Sweet Home 3D files needed to
+// exchange rooms with it. A .sh3d file is a zip archive whose Home.xml entry
+// describes the home.
+//
+// Lengths are in centimetres and the y axis points down, as in EHome. Angles
+// are converted to clockwise degrees; the file itself stores radians.
+package sh3d
+
+import (
+	"errors"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+var (
+	ErrInvalidFile = errors.New("not a valid Sweet Home 3D file")
+	ErrNoHomeXML   = errors.New("the file has no Home.xml entry, save it again with Sweet Home 3D 5.3 or later")
+)
+
+// homeEntry is the name of the zip entry holding the home.
+const homeEntry = "Home.xml"
+
+// Limits of what Read accepts, so that a small archive can't unpack into an
+// unbounded amount of memory. Files past them are invalid.
+const (
+	MaxHomeXMLSize = 50 << 20 // Uncompressed size of Home.xml, in bytes
+	MaxRooms       = 100
+	MaxRoomPoints  = 1000 // Per room
+	MaxWalls       = 10_000
+	MaxPieces      = 5000
+)
+
+// Home is the content of a .sh3d file.
+type Home struct {
+	Name       string
+	WallHeight float64
+	Rooms      []Room
+	Walls      []Wall
+	Pieces     []Piece
+}
+
+// Room is a floor area. Rooms, walls and pieces only meet on the same level;
+// the level is empty in homes with a single one.
+type Room struct {
+	Name   string
+	Level  string
+	Points geometry.Polygon
+}
+
+// Wall is a straight wall along the line from its start to its end.
+type Wall struct {
+	Level          string
+	XStart, YStart float64
+	XEnd, YEnd     float64
+	Thickness      float64
+	Height         float64 // Zero to use the wall height of the home
+}
+
+// Piece is a piece of furniture, light, door or window. X and Y give its
+// centre.
+type Piece struct {
+	Name                 string
+	Level                string
+	X, Y                 float64
+	Width, Depth, Height float64
+	Angle                float64 // Clockwise, in degrees
+	Price                string  // Decimal price, empty if unknown
+	DoorOrWindow         bool
+	Visible              bool
+}
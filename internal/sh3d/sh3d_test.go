@@ -0,0 +1,191 @@
+package sh3d
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/WrastAct/EHome/internal/geometry"
+)
+
+// archive returns a zip archive with the given entries, as name, content
+// pairs.
+func archive(t *testing.T, entries ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for i := 0; i+1 < len(entries); i += 2 {
+		w, err := zw.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write([]byte(entries[i+1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func read(data []byte) (*Home, error) {
+	return Read(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestRoundTrip(t *testing.T) {
+	h := &Home{
+		Name:       "Flat",
+		WallHeight: 250,
+		Rooms: []Room{
+			{Name: "Bed & bath", Points: geometry.Polygon{{X: 0, Y: 0}, {X: 400, Y: 0}, {X: 400, Y: 300}, {X: 0, Y: 300}}},
+		},
+		Walls: []Wall{
+			{XStart: 0, YStart: 0, XEnd: 400, YEnd: 0, Thickness: 10},
+			{XStart: 400, YStart: 0, XEnd: 400, YEnd: 300, Thickness: 10, Height: 220.5},
+		},
+		Pieces: []Piece{
+			{Name: "Bed", X: 100, Y: 150, Width: 160, Depth: 200, Height: 50, Price: "499.99", Visible: true},
+			{Name: "Wardrobe", X: 350, Y: 100, Width: 100, Depth: 60, Height: 200, Angle: 90, Visible: true},
+			{Name: "Lamp <old>", X: 20, Y: 20, Width: 30, Depth: 30, Height: 150, Angle: 45},
+			{Name: "Door", X: 200, Y: 0, Width: 90, Depth: 10, Height: 210, Angle: 180, DoorOrWindow: true, Visible: true},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	err := Write(&buf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := read(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, h) {
+		t.Errorf("Read(Write(h)) = %+v, want %+v", got, h)
+	}
+}
+
+func TestReadLevels(t *testing.T) {
+	data := archive(t, homeEntry, `<?xml version='1.0'?>
+<home version='6400' name='House'>
+<level id='ground' name='Ground'/>
+<room level='ground'><point x='0' y='0'/><point x='100' y='0'/><point x='100' y='100'/></room>
+<room level='ground'><point x='0' y='0'/><point x='100' y='0'/></room>
+<furnitureGroup level='upstairs' name='Set'>
+<pieceOfFurniture name='Chair' x='10' y='10' width='40' depth='40' height='90'/>
+<furnitureGroup name='Inner'>
+<light name='Lamp' level='attic' x='1' y='2' width='3' depth='4' height='5' angle='3.1415927'/>
+<pieceOfFurniture name='Table' x='50' y='50' width='80' depth='80' height='75'/>
+</furnitureGroup>
+</furnitureGroup>
+<pieceOfFurniture name='Rug' x='0' y='0' width='10' depth='10' height='1'/>
+</home>`)
+
+	h, err := read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h.Rooms) != 1 || h.Rooms[0].Level != "ground" {
+		t.Errorf("Rooms = %+v, want the one room with three points on level ground", h.Rooms)
+	}
+
+	tests := []struct {
+		name  string
+		level string
+		angle float64
+	}{
+		{"Chair", "upstairs", 0},
+		{"Lamp", "attic", 180},
+		{"Table", "upstairs", 0},
+		{"Rug", "", 0},
+	}
+
+	if len(h.Pieces) != len(tests) {
+		t.Fatalf("got %d pieces, want %d", len(h.Pieces), len(tests))
+	}
+
+	for i, tt := range tests {
+		p := h.Pieces[i]
+		if p.Name != tt.name || p.Level != tt.level || p.Angle != tt.angle {
+			t.Errorf("Pieces[%d] = %s on %q at %g°, want %s on %q at %g°", i, p.Name, p.Level, p.Angle, tt.name, tt.level, tt.angle)
+		}
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	home := func(body string) []byte {
+		return archive(t, homeEntry, "<home name='H'>"+body+"</home>")
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"not a zip archive", []byte("<home/>"), ErrInvalidFile},
+		{"old file without Home.xml", archive(t, "Home", "serialized"), ErrNoHomeXML},
+		{"empty archive", archive(t), ErrInvalidFile},
+		{"wrong root element", archive(t, homeEntry, "<house/>"), ErrInvalidFile},
+		{"empty Home.xml", archive(t, homeEntry, ""), ErrInvalidFile},
+		{"truncated Home.xml", archive(t, homeEntry, "<home><pieceOfFurniture name='Bed' x='1'"), ErrInvalidFile},
+		{"NaN coordinate", home("<pieceOfFurniture x='NaN'/>"), ErrInvalidFile},
+		{"infinite wall", home("<wall xStart='0' yStart='0' xEnd='Inf' yEnd='0'/>"), ErrInvalidFile},
+		{"point not a number", home("<room><point x='a' y='0'/></room>"), ErrInvalidFile},
+		{"too many pieces", home(strings.Repeat("<pieceOfFurniture/>", MaxPieces+1)), ErrInvalidFile},
+		{"too many rooms", home(strings.Repeat("<room><point x='0' y='0'/><point x='1' y='0'/><point x='1' y='1'/></room>", MaxRooms+1)), ErrInvalidFile},
+		{"too many walls", home(strings.Repeat("<wall/>", MaxWalls+1)), ErrInvalidFile},
+		{"too many points", home("<room>" + strings.Repeat("<point x='0' y='0'/>", MaxRoomPoints+1) + "</room>"), ErrInvalidFile},
+	}
+
+	for _, tt := range tests {
+		_, err := read(tt.data)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Read error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestReadLargeHomeXML(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	// The header claims more than the limit, whatever the entry holds.
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               homeEntry,
+		Method:             zip.Store,
+		CompressedSize64:   7,
+		UncompressedSize64: MaxHomeXMLSize + 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = w.Write([]byte("<home/>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = read(buf.Bytes())
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Read error = %v, want %v", err, ErrInvalidFile)
+	}
+}
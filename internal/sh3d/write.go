@@ -0,0 +1,192 @@
+package sh3d
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// homeVersion is the Sweet Home 3D version the written Home.xml claims to
+// come from. Versions from 5.3 on read homes from Home.xml alone.
+const homeVersion = "6400"
+
+// boxEntry holds the 3D model shared by every written piece. Sweet Home 3D
+// stretches it to the size of each piece.
+const boxEntry = "box.obj"
+
+const boxModel = `# Unit box
+v -0.5 0 -0.5
+v 0.5 0 -0.5
+v 0.5 0 0.5
+v -0.5 0 0.5
+v -0.5 1 -0.5
+v 0.5 1 -0.5
+v 0.5 1 0.5
+v -0.5 1 0.5
+f 1 2 3 4
+f 8 7 6 5
+f 1 5 6 2
+f 2 6 7 3
+f 3 7 8 4
+f 4 8 5 1
+`
+
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+// element writes an empty element, or only its start tag when open is true.
+// Attributes are given as name, value pairs and left out when the value is
+// empty.
+func (w *writer) element(name string, open bool, attributes ...string) {
+	w.printf("<%s", name)
+
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if attributes[i+1] == "" {
+			continue
+		}
+		w.printf(" %s='%s'", attributes[i], escape(attributes[i+1]))
+	}
+
+	if open {
+		w.printf(">\n")
+	} else {
+		w.printf("/>\n")
+	}
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 32)
+}
+
+// Write writes the home as a .sh3d file. Walls which meet end to start are
+// joined, so that closed outlines get clean corners.
+func Write(out io.Writer, h *Home) error {
+	archive := zip.NewWriter(out)
+
+	entry, err := archive.Create(homeEntry)
+	if err != nil {
+		return err
+	}
+
+	w := &writer{w: bufio.NewWriter(entry)}
+
+	w.printf("<?xml version='1.0'?>\n")
+
+	wallHeight := ""
+	if h.WallHeight > 0 {
+		wallHeight = num(h.WallHeight)
+	}
+
+	w.element("home", true, "version", homeVersion, "name", h.Name, "wallHeight", wallHeight)
+
+	for i, wall := range h.Walls {
+		var atStart, atEnd string
+
+		for j, other := range h.Walls {
+			if j == i || other.Level != wall.Level {
+				continue
+			}
+			if other.XEnd == wall.XStart && other.YEnd == wall.YStart {
+				atStart = wallID(j)
+			}
+			if other.XStart == wall.XEnd && other.YStart == wall.YEnd {
+				atEnd = wallID(j)
+			}
+		}
+
+		height := ""
+		if wall.Height > 0 {
+			height = num(wall.Height)
+		}
+
+		w.element("wall", false,
+			"id", wallID(i),
+			"wallAtStart", atStart,
+			"wallAtEnd", atEnd,
+			"xStart", num(wall.XStart),
+			"yStart", num(wall.YStart),
+			"xEnd", num(wall.XEnd),
+			"yEnd", num(wall.YEnd),
+			"height", height,
+			"thickness", num(wall.Thickness),
+		)
+	}
+
+	for _, room := range h.Rooms {
+		w.element("room", true, "name", room.Name, "areaVisible", "true")
+		for _, pt := range room.Points {
+			w.element("point", false, "x", num(pt.X), "y", num(pt.Y))
+		}
+		w.printf("</room>\n")
+	}
+
+	for _, p := range h.Pieces {
+		visible := ""
+		if !p.Visible {
+			visible = "false"
+		}
+
+		kind := "pieceOfFurniture"
+		if p.DoorOrWindow {
+			kind = "doorOrWindow"
+		}
+
+		w.element(kind, false,
+			"name", p.Name,
+			"model", boxEntry,
+			"x", num(p.X),
+			"y", num(p.Y),
+			"width", num(p.Width),
+			"depth", num(p.Depth),
+			"height", num(p.Height),
+			"angle", num(math.Mod(p.Angle, 360)*math.Pi/180),
+			"price", p.Price,
+			"visible", visible,
+		)
+	}
+
+	w.printf("</home>\n")
+
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if w.err != nil {
+		return w.err
+	}
+
+	entry, err = archive.Create(boxEntry)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(entry, boxModel)
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func wallID(i int) string {
+	return "wall" + strconv.Itoa(i)
+}
@@ -0,0 +1,3 @@
+ALTER TABLE room_furniture DROP CONSTRAINT IF EXISTS room_furniture_rotation_check;
+
+ALTER TABLE room_furniture DROP COLUMN IF EXISTS rotation;
\ No newline at end of file
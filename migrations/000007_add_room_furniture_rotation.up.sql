@@ -0,0 +1,3 @@
+ALTER TABLE room_furniture ADD COLUMN IF NOT EXISTS rotation DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+ALTER TABLE room_furniture ADD CONSTRAINT room_furniture_rotation_check CHECK (rotation >= 0 AND rotation < 360);
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- The old key allows a single item per position, so only the first of several
+-- placements sharing one is kept.
+DELETE FROM room_furniture a USING room_furniture b
+WHERE a.placement_id > b.placement_id
+    AND a.furniture_id = b.furniture_id AND a.room_id = b.room_id
+    AND a.x = b.x AND a.y = b.y;
+
+ALTER TABLE room_furniture DROP COLUMN IF EXISTS placement_id;
+
+ALTER TABLE room_furniture ADD PRIMARY KEY (furniture_id, room_id, x, y);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE room_furniture DROP CONSTRAINT IF EXISTS room_furniture_pkey;
+
+ALTER TABLE room_furniture ADD COLUMN IF NOT EXISTS placement_id BIGSERIAL PRIMARY KEY;
\ No newline at end of file
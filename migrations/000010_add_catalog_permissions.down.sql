@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS furniture_changes;
+
+DELETE FROM permissions WHERE code = 'catalog:write';
\ No newline at end of file
@@ -0,0 +1,14 @@
+INSERT INTO permissions (code)
+VALUES
+    ('catalog:write');
+
+CREATE TABLE IF NOT EXISTS furniture_changes (
+    id bigserial PRIMARY KEY,
+    furniture_id bigint NOT NULL,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    action text NOT NULL,
+    furniture jsonb NOT NULL,
+    changed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS furniture_changes_furniture_id_idx ON furniture_changes (furniture_id);
\ No newline at end of file
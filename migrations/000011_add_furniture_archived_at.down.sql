@@ -0,0 +1,6 @@
+ALTER TABLE room_furniture DROP CONSTRAINT IF EXISTS room_furniture_furniture_id_fkey;
+
+ALTER TABLE room_furniture ADD CONSTRAINT room_furniture_furniture_id_fkey
+    FOREIGN KEY (furniture_id) REFERENCES furniture(furniture_id) ON DELETE CASCADE;
+
+ALTER TABLE furniture DROP COLUMN IF EXISTS archived_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE furniture ADD COLUMN IF NOT EXISTS archived_at timestamp(0) with time zone;
+
+ALTER TABLE room_furniture DROP CONSTRAINT IF EXISTS room_furniture_furniture_id_fkey;
+
+ALTER TABLE room_furniture ADD CONSTRAINT room_furniture_furniture_id_fkey
+    FOREIGN KEY (furniture_id) REFERENCES furniture(furniture_id) ON DELETE RESTRICT;
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS room_visibility_idx;
+DROP INDEX IF EXISTS room_user_id_idx;
+
+ALTER TABLE room DROP CONSTRAINT IF EXISTS room_visibility_check;
+
+ALTER TABLE room DROP COLUMN IF EXISTS visibility;
\ No newline at end of file
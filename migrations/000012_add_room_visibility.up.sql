@@ -0,0 +1,6 @@
+ALTER TABLE room ADD COLUMN IF NOT EXISTS visibility text NOT NULL DEFAULT 'private';
+
+ALTER TABLE room ADD CONSTRAINT room_visibility_check CHECK (visibility IN ('private', 'unlisted', 'public'));
+
+CREATE INDEX IF NOT EXISTS room_user_id_idx ON room (user_id);
+CREATE INDEX IF NOT EXISTS room_visibility_idx ON room (visibility);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS room_collaborators;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS room_collaborators (
+    room_id bigint NOT NULL REFERENCES room ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('viewer', 'editor', 'co-owner')),
+    invited_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (room_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS room_collaborators_user_id_idx ON room_collaborators (user_id);
\ No newline at end of file
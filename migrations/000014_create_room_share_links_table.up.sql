@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS room_share_links (
+    id bigserial PRIMARY KEY,
+    hash bytea UNIQUE NOT NULL,
+    room_id bigint NOT NULL REFERENCES room ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    expiry timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    revoked_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS room_share_links_room_id_idx ON room_share_links (room_id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS room_revisions;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS room_revisions (
+    id bigserial PRIMARY KEY,
+    room_id bigint NOT NULL REFERENCES room ON DELETE CASCADE,
+    room_version integer NOT NULL,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    snapshot jsonb NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS room_revisions_room_id_idx ON room_revisions (room_id, id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS room_templates;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS room_templates (
+    id bigserial PRIMARY KEY,
+    room_id bigint NOT NULL REFERENCES room ON DELETE CASCADE,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    created_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE room DROP CONSTRAINT IF EXISTS room_budget_check;
+
+ALTER TABLE room DROP COLUMN IF EXISTS budget;
\ No newline at end of file
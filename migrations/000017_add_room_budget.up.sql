@@ -0,0 +1,3 @@
+ALTER TABLE room ADD COLUMN IF NOT EXISTS budget NUMERIC(20, 2);
+
+ALTER TABLE room ADD CONSTRAINT room_budget_check CHECK (budget >= 0);
\ No newline at end of file
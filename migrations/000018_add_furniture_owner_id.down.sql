@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS furniture_owner_id_idx;
+
+ALTER TABLE furniture DROP COLUMN IF EXISTS owner_id;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE furniture ADD COLUMN IF NOT EXISTS owner_id bigint REFERENCES users ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS furniture_owner_id_idx ON furniture (owner_id);
\ No newline at end of file
@@ -0,0 +1,10 @@
+DELETE FROM room_templates WHERE room_id IS NULL;
+
+ALTER TABLE room_templates DROP CONSTRAINT IF EXISTS room_templates_room_id_fkey;
+
+ALTER TABLE room_templates ADD CONSTRAINT room_templates_room_id_fkey
+    FOREIGN KEY (room_id) REFERENCES room ON DELETE CASCADE;
+
+ALTER TABLE room_templates ALTER COLUMN room_id SET NOT NULL;
+
+ALTER TABLE room_templates DROP COLUMN IF EXISTS snapshot;
\ No newline at end of file
@@ -0,0 +1,38 @@
+ALTER TABLE room_templates ADD COLUMN IF NOT EXISTS snapshot jsonb;
+
+-- Existing templates keep the layout their room has right now.
+UPDATE room_templates t
+SET snapshot = jsonb_build_object(
+    'id', r.room_id,
+    'title', r.title,
+    'description', r.room_description,
+    'width', r.room_width,
+    'height', r.room_height,
+    'version', r.version,
+    'visibility', r.visibility,
+    'budget', r.budget,
+    'furniture_list', COALESCE((
+        SELECT jsonb_agg(jsonb_build_object(
+            'id', rf.placement_id,
+            'furniture_id', rf.furniture_id,
+            'room_id', rf.room_id,
+            'x', rf.x,
+            'y', rf.y,
+            'rotation', rf.rotation
+        ) ORDER BY rf.placement_id)
+        FROM room_furniture rf
+        WHERE rf.room_id = r.room_id
+    ), '[]'::jsonb)
+)
+FROM room r
+WHERE r.room_id = t.room_id;
+
+ALTER TABLE room_templates ALTER COLUMN snapshot SET NOT NULL;
+
+-- The source room is only kept for reference, deleting it leaves the template.
+ALTER TABLE room_templates ALTER COLUMN room_id DROP NOT NULL;
+
+ALTER TABLE room_templates DROP CONSTRAINT IF EXISTS room_templates_room_id_fkey;
+
+ALTER TABLE room_templates ADD CONSTRAINT room_templates_room_id_fkey
+    FOREIGN KEY (room_id) REFERENCES room ON DELETE SET NULL;
\ No newline at end of file
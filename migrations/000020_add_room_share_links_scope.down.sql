@@ -0,0 +1,3 @@
+ALTER TABLE room_share_links DROP CONSTRAINT IF EXISTS room_share_links_scope_check;
+
+ALTER TABLE room_share_links DROP COLUMN IF EXISTS scope;
\ No newline at end of file
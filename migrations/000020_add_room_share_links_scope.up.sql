@@ -0,0 +1,5 @@
+ALTER TABLE room_share_links ADD COLUMN IF NOT EXISTS scope text NOT NULL DEFAULT 'room-share';
+
+-- Only room share tokens may be stored here, so a share link can't be mistaken
+-- for any other kind of token.
+ALTER TABLE room_share_links ADD CONSTRAINT room_share_links_scope_check CHECK (scope = 'room-share');
\ No newline at end of file